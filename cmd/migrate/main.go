@@ -0,0 +1,70 @@
+// Command migrate applies the SQL migrations embedded in the binary.
+//
+//	migrate up            apply every pending migration
+//	migrate down [n]      roll back the last n migrations (1 by default)
+//	migrate status        current version, dirty flag and pending versions
+//	migrate force <v>     set the version after fixing a failed migration by hand
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"post-service/internal/config"
+	"post-service/migration"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	m, err := migration.New(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	defer m.Close()
+
+	switch os.Args[1] {
+	case "up":
+		err = m.Up()
+	case "down":
+		n := 1
+		if len(os.Args) > 2 {
+			if n, err = strconv.Atoi(os.Args[2]); err != nil || n < 1 {
+				log.Fatalf("down: invalid count %q", os.Args[2])
+			}
+		}
+		err = m.Down(n)
+	case "force":
+		if len(os.Args) < 3 {
+			usage()
+		}
+		v, convErr := strconv.Atoi(os.Args[2])
+		if convErr != nil {
+			log.Fatalf("force: invalid version %q", os.Args[2])
+		}
+		err = m.Force(v)
+	case "status":
+		var st migration.Status
+		if st, err = m.Status(); err == nil {
+			fmt.Printf("version: %d\ndirty:   %t\npending: %v\n", st.Version, st.Dirty, st.Pending)
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatalf("migrate %s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up | down [n] | status | force <version>")
+	os.Exit(2)
+}
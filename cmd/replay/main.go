@@ -0,0 +1,214 @@
+// Command replay republishes every published post as a synthetic PostCreated
+// event, for downstream services rebuilding their state (e.g. a search index).
+//
+//	go run ./cmd/replay -exchange search_rebuild -rate 200 -checkpoint replay.ckpt
+//	go run ./cmd/replay -author 42 -since 2026-01-01 -dry-run > posts.ndjson
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"post-service/internal/config"
+	"post-service/internal/domain"
+	"post-service/internal/event"
+	"post-service/internal/repository"
+	"post-service/internal/util"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+func main() {
+	exchange := flag.String("exchange", "", "exchange (Kafka topic, stream prefix) to publish to, EVENTS_EXCHANGE by default")
+	routingKey := flag.String("routing-key", "", "routing key of every event instead of post.created")
+	bindings := flag.String("bindings", "", `queues to declare and bind to the exchange: "queue=key,key;queue=key"`)
+	rate := flag.Float64("rate", 100, "events per second, 0 for no limit")
+	batch := flag.Int("batch", 500, "posts read per query")
+	fromID := flag.Int64("from-id", 0, "start after this post id (overrides the checkpoint)")
+	checkpoint := flag.String("checkpoint", "", "file keeping the last replayed post id")
+	author := flag.Int64("author", 0, "only posts of this author id")
+	since := flag.String("since", "", "only posts created at or after this date (2006-01-02 or RFC 3339)")
+	until := flag.String("until", "", "only posts created before this date")
+	dryRun := flag.Bool("dry-run", false, "write the events as NDJSON to stdout instead of publishing")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	if *exchange == "" {
+		*exchange = cfg.EventsExchange
+	}
+
+	filter := repository.PostFilter{AuthorID: *author}
+	if filter.Since, err = parseDate(*since); err != nil {
+		log.Fatalf("-since: %v", err)
+	}
+	if filter.Until, err = parseDate(*until); err != nil {
+		log.Fatalf("-until: %v", err)
+	}
+
+	lastID := *fromID
+	if lastID == 0 && *checkpoint != "" {
+		if lastID, err = readCheckpoint(*checkpoint); err != nil {
+			log.Fatalf("checkpoint: %v", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("postgres: %v", err)
+	}
+	defer pool.Close()
+	hotRanking, err := util.NewHotRanking(util.HotRankingLinear, util.HotWeights{})
+	if err != nil {
+		log.Fatalf("hot ranking: %v", err)
+	}
+	repo := repository.NewPostRepository(repository.NewCluster(pool), hotRanking, 0)
+
+	var publish func(evt event.Event) error
+	if *dryRun {
+		enc := json.NewEncoder(os.Stdout)
+		publish = func(evt event.Event) error {
+			return enc.Encode(event.NewEnvelope(ctx, evt))
+		}
+	} else {
+		publisher := newPublisher(cfg, *exchange, *bindings)
+		defer publisher.Close()
+		var tick <-chan time.Time
+		if *rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / *rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		// A post counts as replayed once the backend confirmed its event, so
+		// the checkpoint never runs ahead of what was delivered
+		publish = func(evt event.Event) error {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return publisher.PublishConfirmed(ctx, evt)
+		}
+	}
+
+	total := 0
+	var failed error
+	for ctx.Err() == nil && failed == nil {
+		posts, err := repo.ListPostsAfterID(ctx, lastID, filter, *batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Fatalf("list posts after %d: %v", lastID, err)
+		}
+		if len(posts) == 0 {
+			break
+		}
+		for i := range posts {
+			var evt event.Event = snapshotEvent(&posts[i])
+			if *routingKey != "" {
+				evt = event.Rerouted{Event: evt, Key: *routingKey}
+			}
+			if failed = publish(evt); failed != nil {
+				break
+			}
+			lastID = posts[i].ID
+			total++
+		}
+		if *checkpoint != "" {
+			if err := writeCheckpoint(*checkpoint, lastID); err != nil {
+				log.Fatalf("checkpoint: %v", err)
+			}
+		}
+		log.Printf("replayed %d posts, last id %d", total, lastID)
+	}
+	if failed != nil && ctx.Err() == nil {
+		log.Fatalf("publish after id %d: %v", lastID, failed)
+	}
+	log.Printf("done: %d posts, last id %d", total, lastID)
+}
+
+func snapshotEvent(p *domain.Post) event.PostCreated {
+	return event.PostCreated{
+		PostID:   p.ID,
+		AuthorID: p.AuthorID,
+		// The mentioned users were notified when the post was published
+		Mentions: []string{},
+		Post:     event.NewPostSnapshot(p),
+	}
+}
+
+func newPublisher(cfg *config.Config, exchange, bindings string) event.EventPublisher {
+	qb, err := event.ParseBindings(bindings)
+	if err != nil {
+		log.Fatalf("-bindings: %v", err)
+	}
+	var rdb *redis.Client
+	if cfg.EventBus == "redis" {
+		rdb = redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPassword, DB: cfg.RedisDB})
+	}
+	publisher, err := event.NewEventPublisher(event.BusConfig{
+		Backend:     cfg.EventBus,
+		Exchange:    exchange,
+		RabbitMQURL: cfg.RabbitMQURL,
+		Bindings:    qb,
+		Rabbit: event.RabbitOptions{
+			Channels:       cfg.EventsPublishChannels,
+			Buffer:         cfg.EventsPublishBuffer,
+			ConfirmTimeout: time.Duration(cfg.EventsConfirmTimeout) * time.Millisecond,
+			EnqueueTimeout: time.Duration(cfg.EventsPublishTimeout) * time.Millisecond,
+		},
+		KafkaBrokers:    event.ParseBrokers(cfg.KafkaBrokers),
+		RedisPartitions: cfg.RedisStreamPartitions,
+	}, rdb)
+	if err != nil {
+		log.Fatalf("event bus: %v", err)
+	}
+	return publisher
+}
+
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func readCheckpoint(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// writeCheckpoint replaces the file atomically, so a crash never leaves it empty.
+func writeCheckpoint(path string, id int64) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(id, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -4,22 +4,31 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"post-service/internal/config"
 	"post-service/internal/event"
 	"post-service/internal/handler"
+	"post-service/internal/logging"
+	"post-service/internal/middleware"
 	"post-service/internal/repository"
 	"post-service/internal/router"
 	"post-service/internal/service"
+	"post-service/internal/storage"
+	"post-service/internal/tracing"
+	"post-service/internal/util"
+	"post-service/migration"
 
 	fiberprometheus "github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -28,19 +37,63 @@ func main() {
 	if err != nil {
 		log.Fatalf("config: %v", err)
 	}
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Tracing
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("tracing shutdown failed", "err", err)
+		}
+	}()
+
 	// PostgreSQL
-	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if cfg.MigrateOnStart {
+		if err := migrate(cfg.DatabaseURL); err != nil {
+			log.Fatalf("migrations: %v", err)
+		}
+	}
+	poolOpts := repository.PoolOptions{
+		MaxConns:          cfg.DatabaseMaxConns,
+		MinConns:          cfg.DatabaseMinConns,
+		MaxConnLifetime:   time.Duration(cfg.DatabaseConnLifetime) * time.Second,
+		HealthCheckPeriod: time.Duration(cfg.DatabaseHealthCheck) * time.Second,
+		SlowQuery:         time.Duration(cfg.DatabaseSlowQuery) * time.Millisecond,
+	}
+	pool, err := repository.NewPool(ctx, cfg.DatabaseURL, poolOpts)
 	if err != nil {
 		log.Fatalf("postgres: %v", err)
 	}
-	defer pool.Close()
 	if err = pool.Ping(ctx); err != nil {
 		log.Fatalf("postgres ping: %v", err)
 	}
+	var replicas []*pgxpool.Pool
+	for _, url := range strings.Split(cfg.DatabaseReplicaURLs, ",") {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		replica, err := repository.NewPool(ctx, url, poolOpts)
+		if err != nil {
+			log.Fatalf("postgres replica: %v", err)
+		}
+		replicas = append(replicas, replica)
+	}
+	cluster := repository.NewCluster(pool, replicas...)
+	defer cluster.Close()
+	go cluster.CheckReplicas(ctx, time.Duration(cfg.DatabaseReplicaCheckMs)*time.Millisecond)
 
 	// Redis
 	rdb := redis.NewClient(&redis.Options{
@@ -48,34 +101,102 @@ func main() {
 		Password: cfg.RedisPassword,
 		DB:       cfg.RedisDB,
 	})
+	if err = redisotel.InstrumentTracing(rdb); err != nil {
+		log.Fatalf("redis tracing: %v", err)
+	}
 	if err = rdb.Ping(ctx).Err(); err != nil {
 		log.Fatalf("redis: %v", err)
 	}
 	defer rdb.Close()
 
 	// Dependencies
-	repo := repository.NewPostRepository(pool)
+	hotRanking, err := util.NewHotRanking(cfg.HotRanking, util.HotWeights{
+		Likes:    cfg.HotLikesWeight,
+		Comments: cfg.HotCommentsWeight,
+		Views:    cfg.HotViewsWeight,
+		Gravity:  cfg.HotGravity,
+	})
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	repo := repository.NewPostRepository(cluster, hotRanking, time.Duration(cfg.DatabaseStmtTimeout)*time.Millisecond)
 
-	publisher, err := event.NewPublisher(cfg.RabbitMQURL)
+	bindings, err := event.ParseBindings(cfg.EventsQueueBindings)
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	publisher, subscriber, err := event.NewBus(event.BusConfig{
+		Backend:     cfg.EventBus,
+		Exchange:    cfg.EventsExchange,
+		RabbitMQURL: cfg.RabbitMQURL,
+		Bindings:    bindings,
+		Rabbit: event.RabbitOptions{
+			Channels:       cfg.EventsPublishChannels,
+			Buffer:         cfg.EventsPublishBuffer,
+			ConfirmTimeout: time.Duration(cfg.EventsConfirmTimeout) * time.Millisecond,
+			EnqueueTimeout: time.Duration(cfg.EventsPublishTimeout) * time.Millisecond,
+		},
+		KafkaBrokers:    event.ParseBrokers(cfg.KafkaBrokers),
+		KafkaGroupID:    cfg.KafkaGroupID,
+		RedisPartitions: cfg.RedisStreamPartitions,
+		RedisGroup:      cfg.RedisStreamGroup,
+		RedisClaimIdle:  time.Duration(cfg.RedisStreamClaimIdle) * time.Millisecond,
+		Prefetch:        cfg.ConsumerPrefetch,
+	}, rdb)
 	if err != nil {
-		log.Fatalf("rabbitmq publisher: %v", err)
+		log.Fatalf("event bus: %v", err)
 	}
 	defer publisher.Close()
 
-	postService := service.NewPostService(repo, rdb, publisher)
+	blob, err := storage.New(ctx, storage.Config{
+		Backend:     cfg.StorageBackend,
+		LocalDir:    cfg.StorageLocalDir,
+		PublicURL:   cfg.StoragePublicURL,
+		SigningKey:  cfg.StorageSigningKey,
+		S3Endpoint:  cfg.S3Endpoint,
+		S3AccessKey: cfg.S3AccessKey,
+		S3SecretKey: cfg.S3SecretKey,
+		S3Bucket:    cfg.S3Bucket,
+		S3Region:    cfg.S3Region,
+		S3UseSSL:    cfg.S3UseSSL,
+	})
+	if err != nil {
+		log.Fatalf("storage: %v", err)
+	}
+	urlTTL := time.Duration(cfg.UploadURLTTLSecs) * time.Second
+
+	postService := service.NewPostService(repo, rdb, publisher, blob, urlTTL)
 	postHandler := handler.NewPostHandler(postService)
+	uploadHandler := handler.NewUploadHandler(service.NewUploadService(repo, blob, cfg.UploadMaxBytes, urlTTL), blob)
 
-	consumer, err := event.NewConsumer(cfg.RabbitMQURL, repo)
+	consumer, err := event.NewConsumer(subscriber, repo, cfg.ConsumerWorkers, cfg.UserDeletedPolicy, postService.InvalidateAuthorStats)
 	if err != nil {
-		log.Fatalf("rabbitmq: %v", err)
+		log.Fatalf("config: %v", err)
 	}
 	defer consumer.Close()
 
 	// Event consumer in goroutine
-	go consumer.Start(ctx)
+	consumerDone := make(chan struct{})
+	go func() {
+		consumer.Start(ctx)
+		close(consumerDone)
+	}()
+
+	if cfg.ArchiveAfterDays > 0 {
+		archiver := service.NewArchiver(repo,
+			time.Duration(cfg.ArchiveAfterDays)*24*time.Hour,
+			time.Duration(cfg.ArchiveIntervalMinutes)*time.Minute,
+			cfg.ArchiveBatchSize)
+		go archiver.Run(ctx)
+	}
 
 	// Fiber
 	app := fiber.New(fiber.Config{
+		// Bodies past the default limit are streamed rather than buffered, so
+		// the per-route limits (see router.Setup) apply before they are read;
+		// multipart forms are parsed by the upload handler, not ahead of it
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -90,7 +211,8 @@ func main() {
 	prom.RegisterAt(app, "/metrics")
 	app.Use(prom.Middleware)
 
-	app.Use(logger.New())
+	app.Use(middleware.Tracing())
+	app.Use(middleware.RequestLogger())
 	app.Use(recover.New())
 
 	// Health
@@ -106,7 +228,7 @@ func main() {
 		return c.SendString("OK")
 	})
 	app.Get("/health/ready", func(c *fiber.Ctx) error {
-		if err := pool.Ping(c.Context()); err != nil {
+		if err := pool.Ping(c.UserContext()); err != nil {
 			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 				"status": "unhealthy", "database": err.Error(),
 			})
@@ -115,23 +237,46 @@ func main() {
 	})
 
 	// Routes
-	router.Setup(app, postHandler)
+	// Room for the multipart envelope around the largest upload
+	router.Setup(app, postHandler, uploadHandler, int(cfg.UploadMaxBytes)+1<<20)
 
 	// Start
 	go func() {
 		addr := fmt.Sprintf("%s:%d", cfg.AppHost, cfg.AppPort)
-		log.Printf("server listening on %s", addr)
+		slog.Info("server listening", "addr", addr)
 		if err := app.Listen(addr); err != nil {
 			log.Fatalf("server: %v", err)
 		}
 	}()
 
 	<-ctx.Done()
-	log.Println("shutting down...")
+	slog.Info("shutting down")
 	shutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := app.ShutdownWithContext(shutCtx); err != nil {
-		log.Printf("shutdown error: %v", err)
+		slog.Error("shutdown failed", "err", err)
+	}
+	select {
+	case <-consumerDone:
+	case <-shutCtx.Done():
+		slog.Warn("consumer drain timed out")
+	}
+	slog.Info("stopped")
+}
+
+func migrate(databaseURL string) error {
+	m, err := migration.New(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		return err
+	}
+	st, err := m.Status()
+	if err != nil {
+		return err
 	}
-	log.Println("stopped")
+	slog.Info("migrations applied", "version", st.Version)
+	return nil
 }
@@ -2,18 +2,97 @@ package domain
 
 import "time"
 
+const (
+	PostStatusPublished = "published"
+	PostStatusDraft     = "draft"
+	// Published posts of a banned author, published again on unban
+	PostStatusHidden = "hidden"
+
+	// Author name of the posts of a deleted account
+	DeletedAuthorUsername = "[deleted]"
+)
+
 type Post struct {
-	ID              int64     `json:"id"`
-	Title           string    `json:"title"`
-	Content         string    `json:"content"`
-	AuthorID        int64     `json:"authorId"`
-	AuthorUsername  string    `json:"authorUsername"`
-	AuthorAvatarURL string    `json:"authorAvatarUrl"`
-	Tags            []string  `json:"tags,omitempty"`
-	Views           int64     `json:"views"`
-	LikesCount      int64     `json:"likesCount"`
-	CommentsCount   int64     `json:"commentsCount"`
-	IsLikedByMe     bool      `json:"isLikedByMe"`
-	CreatedAt       time.Time `json:"createdAt"`
-	UpdatedAt       time.Time `json:"updatedAt"`
+	ID               int64        `json:"id"`
+	Title            string       `json:"title"`
+	Content          string       `json:"content"`
+	ContentFormat    string       `json:"contentFormat"`
+	ContentHTML      string       `json:"contentHtml"`
+	ContentText      string       `json:"-"`
+	Excerpt          string       `json:"excerpt"`
+	AuthorID         int64        `json:"authorId"`
+	AuthorUsername   string       `json:"authorUsername"`
+	AuthorAvatarURL  string       `json:"authorAvatarUrl"`
+	Tags             []string     `json:"tags,omitempty"`
+	Attachments      []Attachment `json:"attachments,omitempty"`
+	Mentions         []string     `json:"mentions,omitempty"`
+	Status           string       `json:"status"`
+	Views            int64        `json:"views"`
+	LikesCount       int64        `json:"likesCount"`
+	CommentsCount    int64        `json:"commentsCount"`
+	IsLikedByMe      bool         `json:"isLikedByMe"`
+	IsBookmarkedByMe bool         `json:"isBookmarkedByMe"`
+	CreatedAt        time.Time    `json:"createdAt"`
+	UpdatedAt        time.Time    `json:"updatedAt"`
+	DeletedAt        *time.Time   `json:"deletedAt,omitempty"`
+	Archived         bool         `json:"archived,omitempty"`
+}
+
+type Attachment struct {
+	ID        int64  `json:"id"`
+	URL       string `json:"url"`
+	Key       string `json:"key,omitempty"`
+	MimeType  string `json:"mimeType"`
+	SizeBytes int64  `json:"sizeBytes"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	AltText   string `json:"altText,omitempty"`
+}
+
+// PostListItem is the projection of a post used by list endpoints:
+// the excerpt replaces the full body.
+type PostListItem struct {
+	ID               int64     `json:"id"`
+	Title            string    `json:"title"`
+	Excerpt          string    `json:"excerpt"`
+	AuthorID         int64     `json:"authorId"`
+	AuthorUsername   string    `json:"authorUsername"`
+	AuthorAvatarURL  string    `json:"authorAvatarUrl"`
+	Tags             []string  `json:"tags,omitempty"`
+	Views            int64     `json:"views"`
+	LikesCount       int64     `json:"likesCount"`
+	CommentsCount    int64     `json:"commentsCount"`
+	IsLikedByMe      bool      `json:"isLikedByMe"`
+	IsBookmarkedByMe bool      `json:"isBookmarkedByMe"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+type AuthorStats struct {
+	AuthorID      int64      `json:"authorId"`
+	TotalPosts    int64      `json:"totalPosts"`
+	LikesReceived int64      `json:"likesReceived"`
+	Views         int64      `json:"views"`
+	TopTags       []TagCount `json:"topTags"`
+}
+
+type LikedPost struct {
+	Post
+	LikedAt time.Time `json:"likedAt"`
+}
+
+type PostLike struct {
+	UserID  int64     `json:"userId"`
+	LikedAt time.Time `json:"likedAt"`
+}
+
+type BookmarkedPost struct {
+	Post
+	Folder       string    `json:"folder"`
+	BookmarkedAt time.Time `json:"bookmarkedAt"`
 }
@@ -1,23 +1,84 @@
 package dto
 
+import (
+	"post-service/internal/domain"
+	"time"
+)
+
 type CreatePostRequest struct {
-	Title    string   `json:"title"`
-	Content  string   `json:"content"`
-	Tags     []string `json:"tags,omitempty"`
-	AuthorID int64    `json:"-"`
+	Title         string            `json:"title"`
+	Content       string            `json:"content"`
+	ContentFormat string            `json:"contentFormat,omitempty" validate:"omitempty,oneof=plain markdown"`
+	Tags          []string          `json:"tags,omitempty"`
+	Attachments   []AttachmentInput `json:"attachments,omitempty" validate:"omitempty,max=10,dive"`
+	Status        string            `json:"status,omitempty" validate:"omitempty,oneof=draft published"`
+	AuthorID      int64             `json:"-"`
 }
 
 type UpdatePostRequest struct {
-	Title    *string  `json:"title,omitempty"`
-	Content  *string  `json:"content,omitempty"`
-	Tags     []string `json:"tags,omitempty"`
-	AuthorID int64    `json:"author_id"`
+	Title         *string  `json:"title,omitempty"`
+	Content       *string  `json:"content,omitempty"`
+	ContentFormat *string  `json:"contentFormat,omitempty" validate:"omitempty,oneof=plain markdown"`
+	Tags          []string `json:"tags,omitempty"`
+	// Attachments replace the current set when present; [] removes all of them
+	Attachments []AttachmentInput `json:"attachments,omitempty" validate:"omitempty,max=10,dive"`
+	Status      *string           `json:"status,omitempty" validate:"omitempty,oneof=draft published"`
+	AuthorID    int64             `json:"author_id"`
+}
+
+type AttachmentInput struct {
+	URL string `json:"url" validate:"required,url,max=2048"`
+	// Key returned by POST /uploads; the key of another uploader is ignored
+	Key       string `json:"key,omitempty" validate:"max=512"`
+	MimeType  string `json:"mimeType" validate:"required,oneof=image/jpeg image/png image/gif image/webp application/pdf"`
+	SizeBytes int64  `json:"sizeBytes" validate:"gte=0,lte=20971520"`
+	Width     int    `json:"width,omitempty" validate:"gte=0"`
+	Height    int    `json:"height,omitempty" validate:"gte=0"`
+	AltText   string `json:"altText,omitempty" validate:"max=500"`
 }
 
 type ListPostsQuery struct {
-	Limit  int    `query:"limit"`
-	Offset int    `query:"offset"`
-	Sort   string `query:"sort"`
-	Author string `query:"author"`
-	Tag    string `query:"tag"`
+	Limit           int    `query:"limit"`
+	Offset          int    `query:"offset"`
+	Sort            string `query:"sort"`
+	Period          string `query:"period"`
+	Author          string `query:"author"`
+	Tag             string `query:"tag"`
+	IncludeArchived bool   `query:"includeArchived"`
+}
+
+// ListAuthorPostsQuery — status: published (default), draft, deleted, all.
+type ListAuthorPostsQuery struct {
+	Limit           int    `query:"limit"`
+	Offset          int    `query:"offset"`
+	Status          string `query:"status"`
+	IncludeArchived bool   `query:"includeArchived"`
+}
+
+type BookmarkRequest struct {
+	Folder string `json:"folder" validate:"max=100"`
+}
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type PostLikersResponse struct {
+	PostID     int64             `json:"postId"`
+	Count      int64             `json:"count"`
+	Users      []domain.PostLike `json:"users"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// UploadResponse can be passed as is in the attachments of a post.
+type UploadResponse struct {
+	Key       string    `json:"key"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	MimeType  string    `json:"mimeType"`
+	SizeBytes int64     `json:"sizeBytes"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	SHA256    string    `json:"sha256"`
 }
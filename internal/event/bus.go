@@ -0,0 +1,144 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EventPublisher sends outgoing events. Every backend keeps the order of events
+// with the same OrderingKey (the post ID): they land in the same partition.
+// Publish may return before the backend has stored the event (RabbitMQ buffers
+// it); PublishConfirmed waits for that and reports whether it happened.
+type EventPublisher interface {
+	Publish(ctx context.Context, evt Event)
+	PublishConfirmed(ctx context.Context, evt Event) error
+	Close()
+}
+
+// EventSubscriber delivers incoming messages of the given topics (queues,
+// Kafka topics or Redis streams) to handler until ctx is done. The handler
+// acks or nacks each message, possibly after Subscribe has returned and before Close.
+type EventSubscriber interface {
+	Subscribe(ctx context.Context, topics []string, handler Handler) error
+	Close()
+}
+
+type Handler func(ctx context.Context, msg Message) error
+
+type Message struct {
+	Topic string
+	Key   string
+	Body  []byte
+	// Headers carry the trace context of the publisher, when the backend has them.
+	Headers map[string]string
+	// Time the message was published, zero when the backend does not say.
+	Time time.Time
+	// Ack confirms the message to the backend; unacked messages are redelivered.
+	Ack func()
+	// Nack hands the message back for a later redelivery: RabbitMQ requeues it
+	// and a Redis entry stays pending until it is claimed again. A message
+	// nacked on its last delivery goes to the dead letters of its topic
+	// instead; Kafka has no redeliveries and dead-letters it at once.
+	Nack func()
+}
+
+// maxDeliveries bounds the deliveries of a message that keeps being nacked;
+// RabbitMQ only flags redeliveries, so it allows two.
+const maxDeliveries = 2
+
+type BusConfig struct {
+	Backend string // rabbitmq, kafka, redis, memory
+
+	// Exchange is the destination of outgoing events: the RabbitMQ topic
+	// exchange, the Kafka topic or the Redis stream name prefix.
+	Exchange string
+
+	RabbitMQURL string
+	Bindings    []QueueBinding
+	Rabbit      RabbitOptions
+
+	KafkaBrokers []string
+	KafkaGroupID string
+
+	RedisPartitions int
+	RedisGroup      string
+	// RedisClaimIdle is how long an entry stays pending before it is claimed again.
+	RedisClaimIdle time.Duration
+
+	// Prefetch bounds the incoming messages delivered but not acked yet.
+	Prefetch int
+}
+
+// NewBus builds the publisher and the subscriber of the configured backend.
+func NewBus(cfg BusConfig, rdb *redis.Client) (EventPublisher, EventSubscriber, error) {
+	if cfg.Backend == "memory" {
+		bus := NewMemoryBus()
+		return bus, bus, nil
+	}
+	pub, err := NewEventPublisher(cfg, rdb)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sub EventSubscriber
+	switch cfg.Backend {
+	case "kafka":
+		sub = NewKafkaSubscriber(cfg.KafkaBrokers, cfg.KafkaGroupID, cfg.Prefetch)
+	case "redis":
+		sub = NewRedisSubscriber(rdb, cfg.RedisGroup, cfg.Prefetch, cfg.RedisClaimIdle)
+	default:
+		if sub, err = NewRabbitSubscriber(cfg.RabbitMQURL, cfg.Prefetch); err != nil {
+			pub.Close()
+			return nil, nil, err
+		}
+	}
+	return pub, sub, nil
+}
+
+// NewEventPublisher builds only the publisher, for tools that do not consume.
+func NewEventPublisher(cfg BusConfig, rdb *redis.Client) (EventPublisher, error) {
+	switch cfg.Backend {
+	case "", "rabbitmq":
+		return NewRabbitPublisher(cfg.RabbitMQURL, Topology{Exchange: cfg.Exchange, Bindings: cfg.Bindings}, cfg.Rabbit)
+	case "kafka":
+		return NewKafkaPublisher(cfg.KafkaBrokers, cfg.Exchange), nil
+	case "redis":
+		return NewRedisPublisher(rdb, cfg.Exchange, cfg.RedisPartitions), nil
+	case "memory":
+		return NewMemoryBus(), nil
+	}
+	return nil, fmt.Errorf("unknown event bus %q", cfg.Backend)
+}
+
+// encode wraps evt in its envelope.
+func encode(ctx context.Context, evt Event) (Envelope, []byte, error) {
+	env := NewEnvelope(ctx, evt)
+	data, err := json.Marshal(env)
+	return env, data, err
+}
+
+// partition maps an ordering key to one of n partitions.
+func partition(key string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// ParseBrokers splits a comma separated list of host:port.
+func ParseBrokers(s string) []string {
+	var brokers []string
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	return brokers
+}
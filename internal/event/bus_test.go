@@ -0,0 +1,62 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMemoryBusKeepsOrderPerPost(t *testing.T) {
+	bus := NewMemoryBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []string
+	go func() {
+		bus.Subscribe(ctx, []string{"post.liked", "post.unliked"}, func(_ context.Context, msg Message) error {
+			var env struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(msg.Body, &env)
+			got = append(got, msg.Key+":"+env.Type)
+			return nil
+		})
+	}()
+	for {
+		bus.mu.Lock()
+		n := len(bus.handlers)
+		bus.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	bus.Publish(ctx, PostLiked{PostID: 1, UserID: 7})
+	bus.Publish(ctx, PostCreated{PostID: 2})
+	bus.Publish(ctx, PostUnliked{PostID: 1, UserID: 7})
+
+	want := []string{"1:PostLiked", "1:PostUnliked"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	if n := len(bus.Published()); n != 3 {
+		t.Fatalf("published %d messages, want 3", n)
+	}
+}
+
+func TestPartitionIsStable(t *testing.T) {
+	for _, key := range []string{"1", "42", "100500"} {
+		p := partition(key, 8)
+		if p < 0 || p >= 8 {
+			t.Fatalf("partition(%q) = %d", key, p)
+		}
+		if partition(key, 8) != p {
+			t.Fatalf("partition(%q) is not stable", key)
+		}
+	}
+	if partition("42", 1) != 0 {
+		t.Fatal("single partition must be 0")
+	}
+}
@@ -1,88 +1,234 @@
 package event
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"post-service/internal/logging"
 	"post-service/internal/repository"
+	"post-service/internal/util"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
 )
 
 type incomingEvent struct {
-	Event     string `json:"event"`
-	PostID    int64  `json:"post_id"`
-	UserID    int64  `json:"user_id"`
-	Username  string `json:"username"`
-	AvatarURL string `json:"avatar_url"`
+	Event      string `json:"event"`
+	PostID     int64  `json:"post_id"`
+	UserID     int64  `json:"user_id"`
+	Username   string `json:"username"`
+	AvatarURL  string `json:"avatar_url"`
+	FollowerID int64  `json:"follower_id"`
+	FolloweeID int64  `json:"followee_id"`
+	Tag        string `json:"tag"`
+	Count      int64  `json:"count"`
 }
 
+// Queues, topics or streams of incoming events.
+var ConsumerTopics = []string{"comment_events", "profile_events", "follow_events", "user_events"}
+
+// What happens to the posts of a deleted account
+const (
+	UserDeletedAnonymize  = "anonymize"
+	UserDeletedSoftDelete = "delete"
+)
+
 type Consumer struct {
-	conn *amqp.Connection
-	repo repository.PostRepository
+	sub               EventSubscriber
+	repo              repository.PostRepository
+	workers           int
+	userDeletedPolicy string
+	authorChanged     func(ctx context.Context, authorID int64)
 }
 
-func NewConsumer(amqpURL string, repo repository.PostRepository) (*Consumer, error) {
-	conn, err := amqp.Dial(amqpURL)
-	if err != nil {
-		return nil, err
+// NewConsumer takes authorChanged, which may be nil, to hear about the events
+// that changed the posts of an author, so caches derived from them can be dropped.
+func NewConsumer(sub EventSubscriber, repo repository.PostRepository, workers int, userDeletedPolicy string, authorChanged func(ctx context.Context, authorID int64)) (*Consumer, error) {
+	switch userDeletedPolicy {
+	case UserDeletedAnonymize, UserDeletedSoftDelete:
+	default:
+		return nil, fmt.Errorf("unknown user deleted policy %q", userDeletedPolicy)
 	}
-	return &Consumer{conn: conn, repo: repo}, nil
+	return &Consumer{
+		sub:               sub,
+		repo:              repo,
+		workers:           max(workers, 1),
+		userDeletedPolicy: userDeletedPolicy,
+		authorChanged:     authorChanged,
+	}, nil
 }
 
+// Attempts at an event before it is handed back to the bus, and the pause
+// before each retry (times the attempt number)
+const (
+	handleAttempts = 3
+	handleBackoff  = 200 * time.Millisecond
+)
+
+// Start runs the workers until ctx is done. Every message goes to the worker of
+// its post (user for profile events), so events of one post keep their order.
+// An event that keeps failing is nacked for a later redelivery, until the bus
+// dead-letters it; an event that can never apply is acked. On shutdown the
+// workers finish and settle what they were given before Start returns.
 func (c *Consumer) Start(ctx context.Context) {
-	ch, err := c.conn.Channel()
-	if err != nil {
-		log.Fatalf("[consumer] channel error: %v", err)
+	work := context.WithoutCancel(ctx)
+	queues := make([]chan delivery, c.workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan delivery, 1)
+		wg.Add(1)
+		go func(q chan delivery) {
+			defer wg.Done()
+			for d := range q {
+				err := c.handle(work, d.msg, d.evt)
+				for attempt := 1; retryable(err) && attempt < handleAttempts; attempt++ {
+					time.Sleep(time.Duration(attempt) * handleBackoff)
+					err = c.handle(work, d.msg, d.evt)
+				}
+				if retryable(err) {
+					nack(d.msg)
+				} else {
+					ack(d.msg)
+				}
+			}
+		}(queues[i])
 	}
-	defer ch.Close()
 
-	queues := []string{"comment_events", "profile_events"}
+	slog.InfoContext(ctx, "consumer subscribed", "topics", strings.Join(ConsumerTopics, ","), "workers", c.workers)
+	err := c.sub.Subscribe(ctx, ConsumerTopics, func(ctx context.Context, msg Message) error {
+		var evt incomingEvent
+		if err := json.Unmarshal(msg.Body, &evt); err != nil {
+			eventsConsumed.WithLabelValues("other", "invalid").Inc()
+			ack(msg)
+			return fmt.Errorf("bad payload: %w", err)
+		}
+		queues[partition(dispatchKey(msg.Topic, evt), c.workers)] <- delivery{msg: msg, evt: evt}
+		return nil
+	})
 	for _, q := range queues {
-		ch.QueueDeclare(q, true, false, false, false, nil)
+		close(q)
 	}
+	wg.Wait()
+	if err != nil {
+		slog.ErrorContext(ctx, "consumer subscribe failed", "err", err)
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, "consumer stopped")
+}
 
-	commentMsgs, _ := ch.Consume("comment_events", "", true, false, false, false, nil)
-	profileMsgs, _ := ch.Consume("profile_events", "", true, false, false, false, nil)
+func (c *Consumer) Close() {
+	c.sub.Close()
+}
 
-	log.Println("[consumer] subscribed to comment_events, profile_events")
+type delivery struct {
+	msg Message
+	evt incomingEvent
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("[consumer] stopped")
-			return
-		case msg := <-commentMsgs:
-			c.handle(ctx, "comment_events", string(msg.Body))
-		case msg := <-profileMsgs:
-			c.handle(ctx, "profile_events", string(msg.Body))
-		}
+func ack(msg Message) {
+	if msg.Ack != nil {
+		msg.Ack()
 	}
 }
 
-func (c *Consumer) Close() {
-	c.conn.Close()
+func nack(msg Message) {
+	if msg.Nack != nil {
+		msg.Nack()
+	}
+}
+
+// retryable tells the errors of handle that another attempt may fix, e.g. the
+// database being unreachable, from the events that can never apply: a missing
+// post, or data the database rejects (SQLSTATE classes 22 and 23).
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, repository.ErrPostNotFound) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return !pgerrcode.IsDataException(pgErr.Code) && !pgerrcode.IsIntegrityConstraintViolation(pgErr.Code)
+	}
+	return true
+}
+
+// dispatchKey is the entity whose events must stay ordered.
+func dispatchKey(topic string, evt incomingEvent) string {
+	switch {
+	case topic == "profile_events" || topic == "user_events":
+		return strconv.FormatInt(evt.UserID, 10)
+	case evt.PostID != 0:
+		return strconv.FormatInt(evt.PostID, 10)
+	default:
+		return strconv.FormatInt(evt.FollowerID, 10)
+	}
 }
 
-func (c *Consumer) handle(ctx context.Context, channel, payload string) {
-	var evt incomingEvent
-	if err := json.Unmarshal([]byte(payload), &evt); err != nil {
-		log.Printf("[consumer] bad payload on %s: %v", channel, err)
-		return
+// handle applies evt under a span that continues the trace of its publisher.
+func (c *Consumer) handle(ctx context.Context, msg Message, evt incomingEvent) error {
+	ctx, span := startConsume(ctx, msg, evt.Event)
+	defer span.End()
+	ctx = logging.NewContext(ctx, "")
+	logging.SetPostID(ctx, evt.PostID)
+	logging.SetUserID(ctx, cmp.Or(evt.UserID, evt.FollowerID))
+	if !msg.Time.IsZero() {
+		consumerLag.WithLabelValues(msg.Topic).Observe(time.Since(msg.Time).Seconds())
 	}
 
+	var err error
+	result := "ok"
 	switch evt.Event {
 	case "CommentCreated":
-		if err := c.repo.IncrementComments(ctx, evt.PostID); err != nil {
-			log.Printf("[consumer] IncrementComments(%d): %v", evt.PostID, err)
-		}
+		err = c.repo.IncrementComments(ctx, evt.PostID)
 	case "CommentDeleted":
-		if err := c.repo.DecrementComments(ctx, evt.PostID); err != nil {
-			log.Printf("[consumer] DecrementComments(%d): %v", evt.PostID, err)
+		err = c.repo.DecrementComments(ctx, evt.PostID)
+	case "CommentBulkDeleted":
+		err = c.repo.AdjustComments(ctx, evt.PostID, -evt.Count)
+	case "UserDeleted":
+		if c.userDeletedPolicy == UserDeletedSoftDelete {
+			err = c.repo.SoftDeleteAuthorPosts(ctx, evt.UserID)
+		} else {
+			err = c.repo.AnonymizeAuthorPosts(ctx, evt.UserID)
 		}
+	case "UserBanned", "UserUnbanned":
+		err = c.repo.SetAuthorPostsHidden(ctx, evt.UserID, evt.Event == "UserBanned")
 	case "ProfileUpdated":
-		if err := c.repo.UpdateAuthorInfo(ctx, evt.UserID, evt.Username, evt.AvatarURL); err != nil {
-			log.Printf("[consumer] UpdateAuthorInfo(%d): %v", evt.UserID, err)
+		err = c.repo.UpdateAuthorInfo(ctx, evt.UserID, evt.Username, evt.AvatarURL)
+	case "FollowCreated":
+		if evt.Tag != "" {
+			err = c.repo.AddTagFollow(ctx, evt.FollowerID, util.NormalizeTag(evt.Tag))
+		} else {
+			err = c.repo.AddUserFollow(ctx, evt.FollowerID, evt.FolloweeID)
+		}
+	case "FollowDeleted":
+		if evt.Tag != "" {
+			err = c.repo.RemoveTagFollow(ctx, evt.FollowerID, util.NormalizeTag(evt.Tag))
+		} else {
+			err = c.repo.RemoveUserFollow(ctx, evt.FollowerID, evt.FolloweeID)
+		}
+	default:
+		// Unknown types stay out of the labels, they come from other services
+		eventsConsumed.WithLabelValues("other", "ignored").Inc()
+		return nil
+	}
+	if err != nil {
+		result = "error"
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "event handling failed", "event", evt.Event, "err", err)
+	} else if c.authorChanged != nil {
+		switch evt.Event {
+		case "UserDeleted", "UserBanned", "UserUnbanned", "ProfileUpdated":
+			c.authorChanged(ctx, evt.UserID)
 		}
 	}
+	eventsConsumed.WithLabelValues(evt.Event, result).Inc()
+	return err
 }
@@ -0,0 +1,365 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"post-service/internal/domain"
+	"post-service/internal/repository"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// memRepo keeps posts in a map; methods the consumer does not call panic
+// through the nil embedded interface.
+type memRepo struct {
+	repository.PostRepository
+	posts      map[int64]*domain.Post
+	tagFollows map[int64][]string
+}
+
+func newMemRepo(posts ...domain.Post) *memRepo {
+	r := &memRepo{posts: map[int64]*domain.Post{}, tagFollows: map[int64][]string{}}
+	for i := range posts {
+		p := posts[i]
+		r.posts[p.ID] = &p
+	}
+	return r
+}
+
+func (r *memRepo) AdjustComments(_ context.Context, postID, delta int64) error {
+	if p, ok := r.posts[postID]; ok {
+		p.CommentsCount = max(p.CommentsCount+delta, 0)
+	}
+	return nil
+}
+
+func (r *memRepo) IncrementComments(ctx context.Context, postID int64) error {
+	return r.AdjustComments(ctx, postID, 1)
+}
+
+func (r *memRepo) DecrementComments(ctx context.Context, postID int64) error {
+	return r.AdjustComments(ctx, postID, -1)
+}
+
+func (r *memRepo) AnonymizeAuthorPosts(_ context.Context, authorID int64) error {
+	for _, p := range r.posts {
+		if p.AuthorID == authorID {
+			p.AuthorUsername, p.AuthorAvatarURL = domain.DeletedAuthorUsername, ""
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) SoftDeleteAuthorPosts(_ context.Context, authorID int64) error {
+	now := time.Now()
+	for _, p := range r.posts {
+		if p.AuthorID == authorID && p.DeletedAt == nil {
+			p.DeletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) SetAuthorPostsHidden(_ context.Context, authorID int64, hidden bool) error {
+	from, to := domain.PostStatusPublished, domain.PostStatusHidden
+	if !hidden {
+		from, to = to, from
+	}
+	for _, p := range r.posts {
+		if p.AuthorID == authorID && p.Status == from {
+			p.Status = to
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) UpdateAuthorInfo(_ context.Context, authorID int64, username, avatarURL string) error {
+	for _, p := range r.posts {
+		if p.AuthorID == authorID {
+			p.AuthorUsername, p.AuthorAvatarURL = username, avatarURL
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) AddTagFollow(_ context.Context, followerID int64, tag string) error {
+	if !slices.Contains(r.tagFollows[followerID], tag) {
+		r.tagFollows[followerID] = append(r.tagFollows[followerID], tag)
+	}
+	return nil
+}
+
+func (r *memRepo) RemoveTagFollow(_ context.Context, followerID int64, tag string) error {
+	r.tagFollows[followerID] = slices.DeleteFunc(r.tagFollows[followerID], func(t string) bool { return t == tag })
+	return nil
+}
+
+// postState is what the handlers change on a post.
+type postState struct {
+	Username string
+	Avatar   string
+	Status   string
+	Deleted  bool
+	Comments int64
+}
+
+func (r *memRepo) state() map[int64]postState {
+	out := map[int64]postState{}
+	for id, p := range r.posts {
+		out[id] = postState{p.AuthorUsername, p.AuthorAvatarURL, p.Status, p.DeletedAt != nil, p.CommentsCount}
+	}
+	return out
+}
+
+func TestConsumerHandle(t *testing.T) {
+	seed := []domain.Post{
+		{ID: 1, AuthorID: 10, AuthorUsername: "alice", AuthorAvatarURL: "a.png", Status: domain.PostStatusPublished, CommentsCount: 5},
+		{ID: 2, AuthorID: 10, AuthorUsername: "alice", AuthorAvatarURL: "a.png", Status: domain.PostStatusDraft},
+		{ID: 3, AuthorID: 20, AuthorUsername: "bob", Status: domain.PostStatusPublished, CommentsCount: 2},
+	}
+	alice := postState{Username: "alice", Avatar: "a.png", Status: domain.PostStatusPublished, Comments: 5}
+	aliceDraft := postState{Username: "alice", Avatar: "a.png", Status: domain.PostStatusDraft}
+	bob := postState{Username: "bob", Status: domain.PostStatusPublished, Comments: 2}
+
+	tests := []struct {
+		name   string
+		policy string
+		events []incomingEvent
+		want   map[int64]postState
+	}{
+		{
+			name:   "user deleted, anonymize",
+			policy: UserDeletedAnonymize,
+			events: []incomingEvent{{Event: "UserDeleted", UserID: 10}},
+			want: map[int64]postState{
+				1: {Username: domain.DeletedAuthorUsername, Status: domain.PostStatusPublished, Comments: 5},
+				2: {Username: domain.DeletedAuthorUsername, Status: domain.PostStatusDraft},
+				3: bob,
+			},
+		},
+		{
+			name:   "user deleted, soft delete",
+			policy: UserDeletedSoftDelete,
+			events: []incomingEvent{{Event: "UserDeleted", UserID: 10}},
+			want: map[int64]postState{
+				1: {Username: "alice", Avatar: "a.png", Status: domain.PostStatusPublished, Deleted: true, Comments: 5},
+				2: {Username: "alice", Avatar: "a.png", Status: domain.PostStatusDraft, Deleted: true},
+				3: bob,
+			},
+		},
+		{
+			name:   "user banned hides published posts",
+			events: []incomingEvent{{Event: "UserBanned", UserID: 10}},
+			want: map[int64]postState{
+				1: {Username: "alice", Avatar: "a.png", Status: domain.PostStatusHidden, Comments: 5},
+				2: aliceDraft,
+				3: bob,
+			},
+		},
+		{
+			name: "user unbanned restores them, drafts stay drafts",
+			events: []incomingEvent{
+				{Event: "UserBanned", UserID: 10},
+				{Event: "UserUnbanned", UserID: 10},
+			},
+			want: map[int64]postState{1: alice, 2: aliceDraft, 3: bob},
+		},
+		{
+			name:   "comment bulk deleted",
+			events: []incomingEvent{{Event: "CommentBulkDeleted", PostID: 1, Count: 3}},
+			want: map[int64]postState{
+				1: {Username: "alice", Avatar: "a.png", Status: domain.PostStatusPublished, Comments: 2},
+				2: aliceDraft,
+				3: bob,
+			},
+		},
+		{
+			name:   "comment bulk deleted does not go below zero",
+			events: []incomingEvent{{Event: "CommentBulkDeleted", PostID: 3, Count: 10}},
+			want: map[int64]postState{
+				1: alice,
+				2: aliceDraft,
+				3: {Username: "bob", Status: domain.PostStatusPublished},
+			},
+		},
+		{
+			name:   "unknown event is ignored",
+			events: []incomingEvent{{Event: "UserRenamed", UserID: 10}},
+			want:   map[int64]postState{1: alice, 2: aliceDraft, 3: bob},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			if policy == "" {
+				policy = UserDeletedAnonymize
+			}
+			repo := newMemRepo(seed...)
+			c, err := NewConsumer(NewMemoryBus(), repo, 1, policy, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, evt := range tt.events {
+				c.handle(context.Background(), Message{}, evt)
+			}
+			if got := repo.state(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumerTagFollowsAreNormalized(t *testing.T) {
+	repo := newMemRepo()
+	c, err := NewConsumer(NewMemoryBus(), repo, 1, UserDeletedAnonymize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, evt := range []incomingEvent{
+		{Event: "FollowCreated", FollowerID: 1, Tag: "#GoLang"},
+		{Event: "FollowCreated", FollowerID: 1, Tag: " golang "},
+		{Event: "FollowCreated", FollowerID: 1, Tag: "Rust"},
+		{Event: "FollowDeleted", FollowerID: 1, Tag: "#RUST"},
+	} {
+		c.handle(context.Background(), Message{}, evt)
+	}
+	if got := repo.tagFollows[1]; !slices.Equal(got, []string{"golang"}) {
+		t.Errorf("tag follows = %v, want [golang]", got)
+	}
+}
+
+func TestConsumerReportsChangedAuthors(t *testing.T) {
+	var changed []int64
+	c, err := NewConsumer(NewMemoryBus(), newMemRepo(domain.Post{ID: 1, AuthorID: 10}), 1, UserDeletedAnonymize,
+		func(_ context.Context, authorID int64) { changed = append(changed, authorID) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, evt := range []incomingEvent{
+		{Event: "UserBanned", UserID: 10},
+		{Event: "UserUnbanned", UserID: 11},
+		{Event: "ProfileUpdated", UserID: 12, Username: "carol"},
+		{Event: "UserDeleted", UserID: 13},
+		{Event: "CommentCreated", PostID: 1},
+		{Event: "FollowCreated", FollowerID: 14, Tag: "go"},
+	} {
+		c.handle(context.Background(), Message{}, evt)
+	}
+	if !slices.Equal(changed, []int64{10, 11, 12, 13}) {
+		t.Errorf("changed authors = %v, want [10 11 12 13]", changed)
+	}
+}
+
+// failingRepo fails the comment counters and tag follows: the database is down
+// for increments, the post is gone for decrements and the tag is too long for
+// its column.
+type failingRepo struct {
+	*memRepo
+	calls int
+}
+
+func (r *failingRepo) IncrementComments(context.Context, int64) error {
+	r.calls++
+	return errors.New("connection refused")
+}
+
+func (r *failingRepo) DecrementComments(context.Context, int64) error {
+	r.calls++
+	return fmt.Errorf("decrement comments: %w", repository.ErrPostNotFound)
+}
+
+func (r *failingRepo) AddTagFollow(context.Context, int64, string) error {
+	r.calls++
+	return fmt.Errorf("add tag follow: %w", &pgconn.PgError{Code: pgerrcode.StringDataRightTruncationDataException})
+}
+
+func TestConsumerSettlesFailedEvents(t *testing.T) {
+	bus := NewMemoryBus()
+	repo := &failingRepo{memRepo: newMemRepo()}
+	c, err := NewConsumer(bus, repo, 1, UserDeletedAnonymize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(stopped)
+	}()
+	for len(bus.handlersOf("comment_events")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var settled []string
+	deliver := func(topic, event string) {
+		bus.Deliver(ctx, Message{
+			Topic: topic,
+			Body:  []byte(`{"event":"` + event + `","post_id":1,"follower_id":2,"tag":"` + strings.Repeat("x", 101) + `"}`),
+			Ack:   func() { settled = append(settled, event+" acked") },
+			Nack:  func() { settled = append(settled, event+" nacked") },
+		})
+	}
+	deliver("comment_events", "CommentCreated")
+	deliver("comment_events", "CommentDeleted")
+	deliver("follow_events", "FollowCreated")
+	cancel()
+	<-stopped
+
+	// Only the outage is retried; the events that can never apply are acked
+	if want := []string{"CommentCreated nacked", "CommentDeleted acked", "FollowCreated acked"}; !slices.Equal(settled, want) {
+		t.Errorf("settled = %v, want %v", settled, want)
+	}
+	if repo.calls != handleAttempts+2 {
+		t.Errorf("repository calls = %d, want %d", repo.calls, handleAttempts+2)
+	}
+}
+
+func TestNewConsumerRejectsUnknownPolicy(t *testing.T) {
+	if _, err := NewConsumer(NewMemoryBus(), newMemRepo(), 1, "purge", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestConsumerMetrics(t *testing.T) {
+	c, err := NewConsumer(NewMemoryBus(), newMemRepo(domain.Post{ID: 1}), 1, UserDeletedAnonymize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := eventsConsumed.WithLabelValues("CommentCreated", "ok")
+	ignored := eventsConsumed.WithLabelValues("other", "ignored")
+	okBefore, ignoredBefore := testutil.ToFloat64(ok), testutil.ToFloat64(ignored)
+	lagBefore := testutil.CollectAndCount(consumerLag)
+
+	msg := Message{Topic: "comment_events", Time: time.Now().Add(-time.Second)}
+	c.handle(context.Background(), msg, incomingEvent{Event: "CommentCreated", PostID: 1})
+	c.handle(context.Background(), Message{}, incomingEvent{Event: "UserRenamed", UserID: 1})
+
+	if got := testutil.ToFloat64(ok) - okBefore; got != 1 {
+		t.Errorf("ok = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(ignored) - ignoredBefore; got != 1 {
+		t.Errorf("ignored = %v, want 1", got)
+	}
+	// Only the stamped message is observed, under its topic
+	if got := testutil.CollectAndCount(consumerLag) - lagBefore; got != 1 {
+		t.Errorf("lag series = %d, want 1", got)
+	}
+}
+
+func TestStreamIDTime(t *testing.T) {
+	if got := streamIDTime("1700000000123-4"); !got.Equal(time.UnixMilli(1700000000123)) {
+		t.Errorf("streamIDTime = %v", got)
+	}
+	if got := streamIDTime("bad"); !got.IsZero() {
+		t.Errorf("streamIDTime(bad) = %v, want zero", got)
+	}
+}
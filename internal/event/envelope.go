@@ -0,0 +1,63 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const Producer = "post-service"
+
+// Event is a typed payload of an outgoing event. The version is bumped on every
+// incompatible change of the payload (see schema_test.go). Events with the same
+// ordering key (the post ID) are delivered in publish order.
+type Event interface {
+	EventType() string
+	EventVersion() int
+	OrderingKey() string
+}
+
+// Envelope is the wire format of every outgoing event.
+type Envelope struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Producer   string    `json:"producer"`
+	TraceID    string    `json:"traceId,omitempty"`
+	Payload    Event     `json:"payload"`
+}
+
+func NewEnvelope(ctx context.Context, evt Event) Envelope {
+	return Envelope{
+		ID:         uuid.NewString(),
+		Type:       evt.EventType(),
+		Version:    evt.EventVersion(),
+		OccurredAt: time.Now().UTC(),
+		Producer:   Producer,
+		TraceID:    TraceIDFromContext(ctx),
+		Payload:    evt,
+	}
+}
+
+type traceIDKey struct{}
+
+// ContextWithTraceID attaches the ID that ties an event to the request that
+// caused it when the request is not traced; RequestLogger sets the request ID.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext is the ID of the OpenTelemetry trace under way, or else
+// the ID set with ContextWithTraceID.
+func TraceIDFromContext(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	if id, ok := ctx.Value(traceIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
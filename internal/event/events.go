@@ -0,0 +1,146 @@
+package event
+
+import (
+	"post-service/internal/domain"
+	"strconv"
+	"time"
+)
+
+// Registry lists every outgoing event; each one has a committed JSON Schema.
+var Registry = []Event{
+	PostCreated{},
+	PostUpdated{},
+	PostDeleted{},
+	PostAttachmentsRemoved{},
+	PostLiked{},
+	PostUnliked{},
+	PostBookmarked{},
+	PostUnbookmarked{},
+}
+
+// PostSnapshot is the state of a post at the moment of the event.
+type PostSnapshot struct {
+	ID              int64     `json:"id"`
+	Title           string    `json:"title"`
+	Excerpt         string    `json:"excerpt"`
+	ContentFormat   string    `json:"content_format"`
+	AuthorID        int64     `json:"author_id"`
+	AuthorUsername  string    `json:"author_username"`
+	AuthorAvatarURL string    `json:"author_avatar_url"`
+	Tags            []string  `json:"tags"`
+	Status          string    `json:"status"`
+	Views           int64     `json:"views"`
+	LikesCount      int64     `json:"likes_count"`
+	CommentsCount   int64     `json:"comments_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func NewPostSnapshot(p *domain.Post) PostSnapshot {
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return PostSnapshot{
+		ID:              p.ID,
+		Title:           p.Title,
+		Excerpt:         p.Excerpt,
+		ContentFormat:   p.ContentFormat,
+		AuthorID:        p.AuthorID,
+		AuthorUsername:  p.AuthorUsername,
+		AuthorAvatarURL: p.AuthorAvatarURL,
+		Tags:            tags,
+		Status:          p.Status,
+		Views:           p.Views,
+		LikesCount:      p.LikesCount,
+		CommentsCount:   p.CommentsCount,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+type PostCreated struct {
+	PostID   int64        `json:"post_id"`
+	AuthorID int64        `json:"author_id"`
+	Mentions []string     `json:"mentions"`
+	Post     PostSnapshot `json:"post"`
+}
+
+func (PostCreated) EventType() string     { return "PostCreated" }
+func (PostCreated) EventVersion() int     { return 1 }
+func (e PostCreated) OrderingKey() string { return postKey(e.PostID) }
+
+type PostUpdated struct {
+	PostID   int64 `json:"post_id"`
+	AuthorID int64 `json:"author_id"`
+	// Mentions are only the usernames added by this update
+	Mentions []string     `json:"mentions"`
+	Post     PostSnapshot `json:"post"`
+}
+
+func (PostUpdated) EventType() string     { return "PostUpdated" }
+func (PostUpdated) EventVersion() int     { return 1 }
+func (e PostUpdated) OrderingKey() string { return postKey(e.PostID) }
+
+type PostDeleted struct {
+	PostID   int64 `json:"post_id"`
+	AuthorID int64 `json:"author_id"`
+	// Storage keys of the attachments, for the media service to clean up
+	AttachmentKeys []string     `json:"attachment_keys"`
+	Post           PostSnapshot `json:"post"`
+}
+
+func (PostDeleted) EventType() string     { return "PostDeleted" }
+func (PostDeleted) EventVersion() int     { return 1 }
+func (e PostDeleted) OrderingKey() string { return postKey(e.PostID) }
+
+// PostAttachmentsRemoved lists the storage keys an update took off a post,
+// drafts included, for the media service to clean up.
+type PostAttachmentsRemoved struct {
+	PostID         int64    `json:"post_id"`
+	AuthorID       int64    `json:"author_id"`
+	AttachmentKeys []string `json:"attachment_keys"`
+}
+
+func (PostAttachmentsRemoved) EventType() string     { return "PostAttachmentsRemoved" }
+func (PostAttachmentsRemoved) EventVersion() int     { return 1 }
+func (e PostAttachmentsRemoved) OrderingKey() string { return postKey(e.PostID) }
+
+type PostLiked struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (PostLiked) EventType() string     { return "PostLiked" }
+func (PostLiked) EventVersion() int     { return 1 }
+func (e PostLiked) OrderingKey() string { return postKey(e.PostID) }
+
+type PostUnliked struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (PostUnliked) EventType() string     { return "PostUnliked" }
+func (PostUnliked) EventVersion() int     { return 1 }
+func (e PostUnliked) OrderingKey() string { return postKey(e.PostID) }
+
+type PostBookmarked struct {
+	PostID int64  `json:"post_id"`
+	UserID int64  `json:"user_id"`
+	Folder string `json:"folder"`
+}
+
+func (PostBookmarked) EventType() string     { return "PostBookmarked" }
+func (PostBookmarked) EventVersion() int     { return 1 }
+func (e PostBookmarked) OrderingKey() string { return postKey(e.PostID) }
+
+type PostUnbookmarked struct {
+	PostID int64 `json:"post_id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (PostUnbookmarked) EventType() string     { return "PostUnbookmarked" }
+func (PostUnbookmarked) EventVersion() int     { return 1 }
+func (e PostUnbookmarked) OrderingKey() string { return postKey(e.PostID) }
+
+func postKey(id int64) string { return strconv.FormatInt(id, 10) }
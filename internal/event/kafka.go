@@ -0,0 +1,264 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// KafkaPublisher writes every event to one topic; the hash balancer puts all
+// events of a post in the same partition.
+type KafkaPublisher struct {
+	w *kafka.Writer
+}
+
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{w: &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		BatchTimeout:           10 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+	}}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, evt Event) {
+	p.PublishConfirmed(ctx, evt)
+}
+
+// PublishConfirmed returns once the in-sync replicas have the event.
+func (p *KafkaPublisher) PublishConfirmed(ctx context.Context, evt Event) error {
+	env, data, err := encode(ctx, evt)
+	if err != nil {
+		slog.ErrorContext(ctx, "event marshal failed", "event", env.Type, "err", err)
+		eventsFailed.WithLabelValues(env.Type, "encode").Inc()
+		return err
+	}
+	ctx, span, traceHeaders := startPublish(ctx, semconv.MessagingSystemKafka, env, p.w.Topic)
+	defer span.End()
+	headers := []kafka.Header{
+		{Key: "type", Value: []byte(env.Type)},
+		{Key: "routing_key", Value: []byte(RoutingKey(evt))},
+	}
+	for k, v := range traceHeaders {
+		headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	eventsPublished.WithLabelValues(env.Type).Inc()
+	err = p.w.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(evt.OrderingKey()),
+		Value:   data,
+		Time:    env.OccurredAt,
+		Headers: headers,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "event publish failed", "event", env.Type, "err", err)
+		span.SetStatus(codes.Error, err.Error())
+		eventsFailed.WithLabelValues(env.Type, "publish").Inc()
+		return err
+	}
+	eventsConfirmed.WithLabelValues(env.Type).Inc()
+	return nil
+}
+
+func (p *KafkaPublisher) Close() {
+	p.w.Close()
+}
+
+type KafkaSubscriber struct {
+	brokers  []string
+	groupID  string
+	prefetch int
+	reader   *kafka.Reader
+	offsets  *offsetTracker
+	dead     *kafka.Writer
+}
+
+// maxPendingOffsets bounds the messages of a partition fetched and not
+// committed yet; fetching waits while a partition is at the bound.
+const maxPendingOffsets = 10000
+
+func NewKafkaSubscriber(brokers []string, groupID string, prefetch int) *KafkaSubscriber {
+	return &KafkaSubscriber{
+		brokers:  brokers,
+		groupID:  groupID,
+		prefetch: prefetch,
+		offsets:  newOffsetTracker(maxPendingOffsets),
+		dead: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+	}
+}
+
+// Subscribe joins the consumer group. Messages are acked out of order by the
+// workers; the offset of a partition is committed only up to the last message
+// acked with all the messages fetched before it. A nacked message is written
+// to <topic>.dead and then acked, so its partition commits past it; if that
+// write fails the commits stay held back and it is fetched again after a restart.
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, topics []string, handler Handler) error {
+	s.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:       s.brokers,
+		GroupID:       s.groupID,
+		GroupTopics:   topics,
+		QueueCapacity: max(s.prefetch, 1),
+	})
+	for {
+		m, err := s.reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		headers := make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		tp := topicPartition{m.Topic, m.Partition}
+		if s.offsets.full(tp) {
+			slog.WarnContext(ctx, "too many uncommitted offsets, fetching paused", "topic", tp.topic, "partition", tp.partition)
+		}
+		for s.offsets.full(tp) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+		gen := s.offsets.fetched(tp, m.Offset)
+		ack := func() {
+			s.offsets.ack(tp, gen, m.Offset, func(offset int64) {
+				// CommitMessages stores the offset after the message
+				err := s.reader.CommitMessages(context.Background(), kafka.Message{Topic: tp.topic, Partition: tp.partition, Offset: offset})
+				if err != nil {
+					slog.Error("event commit failed", "topic", tp.topic, "partition", tp.partition, "err", err)
+				}
+			})
+		}
+		msg := Message{
+			Topic:   m.Topic,
+			Key:     string(m.Key),
+			Body:    m.Value,
+			Headers: headers,
+			Time:    m.Time,
+			Ack:     ack,
+			Nack: func() {
+				if err := s.deadLetter(m); err != nil {
+					slog.Error("dead-letter failed, partition commits held back", "topic", tp.topic, "partition", tp.partition, "offset", m.Offset, "err", err)
+					return
+				}
+				ack()
+			},
+		}
+		if err := handler(ctx, msg); err != nil {
+			slog.WarnContext(ctx, "event rejected", "topic", m.Topic, "err", err)
+		}
+	}
+}
+
+// deadLetter copies m to <topic>.dead, keeping its key and headers.
+func (s *KafkaSubscriber) deadLetter(m kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := s.dead.WriteMessages(ctx, kafka.Message{
+		Topic:   m.Topic + ".dead",
+		Key:     m.Key,
+		Value:   m.Value,
+		Time:    m.Time,
+		Headers: m.Headers,
+	})
+	if err != nil {
+		return err
+	}
+	slog.Error("event dead-lettered", "topic", m.Topic, "partition", m.Partition, "offset", m.Offset)
+	eventsDeadLettered.WithLabelValues(m.Topic).Inc()
+	return nil
+}
+
+func (s *KafkaSubscriber) Close() {
+	if s.reader != nil {
+		s.reader.Close()
+	}
+	s.dead.Close()
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// offsetTracker finds the offsets that are safe to commit: an offset is done
+// once it and every offset fetched before it in its partition are acked.
+type offsetTracker struct {
+	mu         sync.Mutex
+	limit      int
+	partitions map[topicPartition]*partitionOffsets
+}
+
+type partitionOffsets struct {
+	gen     int            // bumped when the partition is fetched again from an earlier offset
+	pending []int64        // fetched and not committed yet, in fetch order
+	acked   map[int64]bool // acked among pending
+}
+
+func newOffsetTracker(limit int) *offsetTracker {
+	return &offsetTracker{limit: limit, partitions: map[topicPartition]*partitionOffsets{}}
+}
+
+// full tells whether tp holds limit offsets not committed yet.
+func (t *offsetTracker) full(tp topicPartition) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[tp]
+	return p != nil && len(p.pending) >= t.limit
+}
+
+// fetched records a message and returns the generation its ack belongs to.
+// After a rebalance the partition restarts at its committed offset: the acks
+// of the messages fetched before are then ignored.
+func (t *offsetTracker) fetched(tp topicPartition, offset int64) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[tp]
+	if p == nil {
+		p = &partitionOffsets{acked: map[int64]bool{}}
+		t.partitions[tp] = p
+	}
+	if n := len(p.pending); n > 0 && offset <= p.pending[n-1] {
+		p.gen++
+		p.pending, p.acked = nil, map[int64]bool{}
+	}
+	p.pending = append(p.pending, offset)
+	return p.gen
+}
+
+// ack marks offset done and calls commit with the highest offset now done,
+// if any. Commits run under the lock so they reach the broker in order.
+func (t *offsetTracker) ack(tp topicPartition, gen int, offset int64, commit func(offset int64)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partitions[tp]
+	if p == nil || p.gen != gen {
+		return
+	}
+	p.acked[offset] = true
+	n := 0
+	for n < len(p.pending) && p.acked[p.pending[n]] {
+		delete(p.acked, p.pending[n])
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	last := p.pending[n-1]
+	p.pending = p.pending[n:]
+	commit(last)
+}
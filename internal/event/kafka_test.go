@@ -0,0 +1,85 @@
+package event
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOffsetTrackerCommitsInOrder(t *testing.T) {
+	tr := newOffsetTracker(10)
+	p0, p1 := topicPartition{"comment_events", 0}, topicPartition{"comment_events", 1}
+	var commits []int64
+	commit := func(offset int64) { commits = append(commits, offset) }
+
+	gen := tr.fetched(p0, 10)
+	for _, off := range []int64{11, 12, 13} {
+		tr.fetched(p0, off)
+	}
+	gen1 := tr.fetched(p1, 5)
+
+	tr.ack(p0, gen, 12, commit) // 10 and 11 are still in flight
+	tr.ack(p0, gen, 11, commit)
+	if len(commits) != 0 {
+		t.Fatalf("committed %v before offset 10 was done", commits)
+	}
+	tr.ack(p1, gen1, 5, commit) // partitions are independent
+	tr.ack(p0, gen, 10, commit)
+	tr.ack(p0, gen, 13, commit)
+	if want := []int64{5, 12, 13}; !slices.Equal(commits, want) {
+		t.Errorf("commits = %v, want %v", commits, want)
+	}
+}
+
+func TestOffsetTrackerNackHoldsCommits(t *testing.T) {
+	tr := newOffsetTracker(10)
+	p := topicPartition{"user_events", 0}
+	var commits []int64
+	commit := func(offset int64) { commits = append(commits, offset) }
+
+	gen := tr.fetched(p, 1)
+	tr.fetched(p, 2) // nacked and not dead-lettered: never acked
+	tr.fetched(p, 3)
+	tr.ack(p, gen, 1, commit)
+	tr.ack(p, gen, 3, commit)
+	if !slices.Equal(commits, []int64{1}) {
+		t.Fatalf("commits = %v, want [1]", commits)
+	}
+
+	// A rebalance fetches the partition again from the committed offset; acks
+	// of the previous fetch no longer count
+	regen := tr.fetched(p, 2)
+	tr.fetched(p, 3)
+	tr.ack(p, gen, 2, commit)
+	tr.ack(p, regen, 2, commit)
+	tr.ack(p, regen, 3, commit)
+	if want := []int64{1, 2, 3}; !slices.Equal(commits, want) {
+		t.Errorf("commits = %v, want %v", commits, want)
+	}
+}
+
+func TestOffsetTrackerIsBounded(t *testing.T) {
+	tr := newOffsetTracker(3)
+	p := topicPartition{"comment_events", 0}
+	commit := func(int64) {}
+
+	gen := tr.fetched(p, 1)
+	tr.fetched(p, 2)
+	if tr.full(p) {
+		t.Fatal("full with 2 of 3 offsets pending")
+	}
+	tr.fetched(p, 3)
+	if !tr.full(p) {
+		t.Fatal("not full with 3 of 3 offsets pending")
+	}
+	if tr.full(topicPartition{"comment_events", 1}) {
+		t.Error("another partition is full")
+	}
+	tr.ack(p, gen, 2, commit)
+	if !tr.full(p) {
+		t.Error("acking behind offset 1 frees room")
+	}
+	tr.ack(p, gen, 1, commit)
+	if tr.full(p) {
+		t.Error("still full after committing offsets 1 and 2")
+	}
+}
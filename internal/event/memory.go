@@ -0,0 +1,72 @@
+package event
+
+import (
+	"context"
+	"sync"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// MemoryBus is an in-process bus for tests. Publish records the message and
+// delivers it synchronously to subscribers of its routing key, so the order per
+// key is the publish order.
+type MemoryBus struct {
+	mu        sync.Mutex
+	published []Message
+	handlers  map[string][]Handler
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: map[string][]Handler{}}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, evt Event) {
+	b.PublishConfirmed(ctx, evt)
+}
+
+func (b *MemoryBus) PublishConfirmed(ctx context.Context, evt Event) error {
+	env, data, err := encode(ctx, evt)
+	if err != nil {
+		return err
+	}
+	ctx, span, headers := startPublish(ctx, semconv.MessagingSystemKey.String("memory"), env, RoutingKey(evt))
+	defer span.End()
+	b.Deliver(ctx, Message{Topic: RoutingKey(evt), Key: evt.OrderingKey(), Body: data, Headers: headers, Time: env.OccurredAt})
+	return nil
+}
+
+// Deliver records msg and hands it to the subscribers of msg.Topic.
+func (b *MemoryBus) Deliver(ctx context.Context, msg Message) {
+	b.mu.Lock()
+	b.published = append(b.published, msg)
+	handlers := append([]Handler(nil), b.handlers[msg.Topic]...)
+	b.mu.Unlock()
+	for _, h := range handlers {
+		h(ctx, msg)
+	}
+}
+
+// Published returns every message seen by the bus, in order.
+func (b *MemoryBus) Published() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.published...)
+}
+
+func (b *MemoryBus) handlersOf(topic string) []Handler {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.handlers[topic]
+}
+
+func (b *MemoryBus) Subscribe(ctx context.Context, topics []string, handler Handler) error {
+	b.mu.Lock()
+	for _, t := range topics {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+	b.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (b *MemoryBus) Close() {}
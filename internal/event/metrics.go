@@ -0,0 +1,34 @@
+package event
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_events_published_total",
+		Help: "Events handed to the event bus.",
+	}, []string{"type"})
+	eventsConfirmed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_events_confirmed_total",
+		Help: "Events acknowledged by the broker.",
+	}, []string{"type"})
+	eventsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_events_failed_total",
+		Help: "Events lost: not encoded, not buffered, nacked, returned as unroutable or not sent.",
+	}, []string{"type", "reason"})
+	eventsConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_events_consumed_total",
+		Help: "Incoming events by type and result: ok, error, ignored (unknown type) or invalid (bad payload).",
+	}, []string{"type", "result"})
+	eventsDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_events_dead_lettered_total",
+		Help: "Incoming events moved to the dead-letter queue, topic or stream after failing their redeliveries.",
+	}, []string{"topic"})
+	consumerLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "post_service_consumer_lag_seconds",
+		Help:    "Time from the publishing of an incoming event to its handling, when the backend stamps messages.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300, 900},
+	}, []string{"topic"})
+)
@@ -0,0 +1,350 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RabbitOptions struct {
+	Channels       int           // channels in the pool, one sender each
+	Buffer         int           // events waiting for a channel, split between the channels
+	ConfirmTimeout time.Duration // wait for the broker ack
+	EnqueueTimeout time.Duration // wait for room in a full buffer before dropping the event
+}
+
+// RabbitPublisher buffers events and sends them over a pool of channels in
+// confirm mode. Each channel has its own buffer and the ordering key picks it,
+// so the events of one post are sent in order. Publish blocks only while that
+// buffer is full, at most EnqueueTimeout.
+type RabbitPublisher struct {
+	conn     *amqp.Connection
+	exchange string
+	opts     RabbitOptions
+
+	mu       sync.RWMutex
+	closed   bool
+	inflight sync.WaitGroup // Publish calls past the closed check
+	queues   []chan outgoing
+	wg       sync.WaitGroup
+}
+
+type outgoing struct {
+	typ  string
+	key  string
+	msg  amqp.Publishing
+	done chan<- error
+}
+
+// confirmChannel is a pooled channel with its own returns, so a return can be
+// matched with the publish it belongs to.
+type confirmChannel struct {
+	ch      *amqp.Channel
+	returns chan amqp.Return
+}
+
+// NewRabbitPublisher declares the topology once; events are then routed by the exchange.
+func NewRabbitPublisher(amqpURL string, topology Topology, opts RabbitOptions) (*RabbitPublisher, error) {
+	conn, err := amqp.Dial(amqpURL)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	defer ch.Close()
+	if err := topology.Declare(ch); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if opts.Channels < 1 {
+		opts.Channels = 1
+	}
+	if opts.ConfirmTimeout <= 0 {
+		opts.ConfirmTimeout = 5 * time.Second
+	}
+	if opts.EnqueueTimeout <= 0 {
+		opts.EnqueueTimeout = time.Second
+	}
+	p := &RabbitPublisher{
+		conn:     conn,
+		exchange: topology.Exchange,
+		opts:     opts,
+		queues:   make([]chan outgoing, opts.Channels),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan outgoing, (opts.Buffer+opts.Channels-1)/opts.Channels)
+		p.wg.Add(1)
+		go p.run(p.queues[i])
+	}
+	return p, nil
+}
+
+func (p *RabbitPublisher) Publish(ctx context.Context, evt Event) {
+	p.enqueue(ctx, evt, nil)
+}
+
+// PublishConfirmed goes through the same buffer as Publish, so it keeps the
+// order of the post, and waits for the broker confirm of the event.
+func (p *RabbitPublisher) PublishConfirmed(ctx context.Context, evt Event) error {
+	done := make(chan error, 1)
+	if err := p.enqueue(ctx, evt, done); err != nil {
+		return err
+	}
+	// The sender settles every event within ConfirmTimeout
+	return <-done
+}
+
+// enqueue hands evt to the sender of its channel; done, when set, gets the
+// outcome of the send.
+func (p *RabbitPublisher) enqueue(ctx context.Context, evt Event, done chan<- error) error {
+	env, data, err := encode(ctx, evt)
+	if err != nil {
+		slog.ErrorContext(ctx, "event marshal failed", "event", env.Type, "err", err)
+		eventsFailed.WithLabelValues(env.Type, "encode").Inc()
+		return err
+	}
+	ctx, span, traceHeaders := startPublish(ctx, semconv.MessagingSystemRabbitMQ, env, p.exchange)
+	defer span.End()
+	headers := amqp.Table{"ordering_key": evt.OrderingKey()}
+	for k, v := range traceHeaders {
+		headers[k] = v
+	}
+	out := outgoing{typ: env.Type, key: RoutingKey(evt), done: done, msg: amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    env.ID,
+		Type:         env.Type,
+		Timestamp:    env.OccurredAt,
+		AppId:        env.Producer,
+		Headers:      headers,
+		Body:         data,
+	}}
+
+	// The lock only guards the closed check: Close waits for inflight, which
+	// is bounded by EnqueueTimeout, before it closes the queues
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		eventsFailed.WithLabelValues(env.Type, "closed").Inc()
+		return errors.New("publisher closed")
+	}
+	p.inflight.Add(1)
+	p.mu.RUnlock()
+	defer p.inflight.Done()
+
+	timer := time.NewTimer(p.opts.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case p.queues[partition(evt.OrderingKey(), len(p.queues))] <- out:
+		eventsPublished.WithLabelValues(env.Type).Inc()
+		return nil
+	case <-ctx.Done():
+		p.dropped(ctx, span, env)
+		return ctx.Err()
+	case <-timer.C:
+		p.dropped(ctx, span, env)
+		return errors.New("publish buffer full")
+	}
+}
+
+func (p *RabbitPublisher) dropped(ctx context.Context, span trace.Span, env Envelope) {
+	slog.ErrorContext(ctx, "publish buffer full, event dropped", "event", env.Type, "event_id", env.ID)
+	span.SetStatus(codes.Error, "buffer full")
+	eventsFailed.WithLabelValues(env.Type, "buffer_full").Inc()
+}
+
+func (p *RabbitPublisher) run(queue <-chan outgoing) {
+	defer p.wg.Done()
+	var cc *confirmChannel
+	for out := range queue {
+		var err error
+		if cc == nil || cc.ch.IsClosed() {
+			if cc, err = p.openChannel(); err != nil {
+				slog.Error("publisher channel failed", "err", err)
+				eventsFailed.WithLabelValues(out.typ, "channel").Inc()
+			}
+		}
+		if err == nil {
+			if err = p.send(cc, out); err != nil {
+				slog.Error("event publish failed", "event", out.typ, "event_id", out.msg.MessageId, "err", err)
+			}
+		}
+		if out.done != nil {
+			out.done <- err
+		}
+	}
+	if cc != nil {
+		cc.ch.Close()
+	}
+}
+
+func (p *RabbitPublisher) openChannel() (*confirmChannel, error) {
+	ch, err := p.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return &confirmChannel{ch: ch, returns: ch.NotifyReturn(make(chan amqp.Return, 1))}, nil
+}
+
+// send publishes with the mandatory flag and waits for the confirm. The broker
+// sends basic.return before the ack, so a return is already buffered by then.
+func (p *RabbitPublisher) send(cc *confirmChannel, out outgoing) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.ConfirmTimeout)
+	defer cancel()
+
+	dc, err := cc.ch.PublishWithDeferredConfirmWithContext(ctx, p.exchange, out.key, true, false, out.msg)
+	if err != nil {
+		eventsFailed.WithLabelValues(out.typ, "publish").Inc()
+		return err
+	}
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		eventsFailed.WithLabelValues(out.typ, "timeout").Inc()
+		// The confirm may still arrive; a fresh channel keeps the next sends unambiguous
+		cc.ch.Close()
+		return err
+	}
+	if !acked {
+		eventsFailed.WithLabelValues(out.typ, "nack").Inc()
+		return errors.New("nacked by broker")
+	}
+	select {
+	case r := <-cc.returns:
+		eventsFailed.WithLabelValues(out.typ, "returned").Inc()
+		return errors.New("unroutable: " + r.ReplyText)
+	default:
+	}
+	eventsConfirmed.WithLabelValues(out.typ).Inc()
+	return nil
+}
+
+// Close stops accepting events and waits until the buffers are sent.
+func (p *RabbitPublisher) Close() {
+	p.mu.Lock()
+	wasClosed := p.closed
+	p.closed = true
+	p.mu.Unlock()
+	if !wasClosed {
+		p.inflight.Wait()
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.wg.Wait()
+	p.conn.Close()
+}
+
+type RabbitSubscriber struct {
+	conn     *amqp.Connection
+	prefetch int
+	ch       *amqp.Channel
+}
+
+func NewRabbitSubscriber(amqpURL string, prefetch int) (*RabbitSubscriber, error) {
+	conn, err := amqp.Dial(amqpURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RabbitSubscriber{conn: conn, prefetch: prefetch}, nil
+}
+
+// Subscribe consumes with manual acks and at most prefetch unacked deliveries.
+// The channel stays open after ctx is done so in-flight messages can be acked;
+// Close releases it and the broker requeues whatever was not acked.
+func (s *RabbitSubscriber) Subscribe(ctx context.Context, topics []string, handler Handler) error {
+	ch, err := s.conn.Channel()
+	if err != nil {
+		return err
+	}
+	s.ch = ch
+	if err := ch.Qos(s.prefetch, 0, false); err != nil {
+		return err
+	}
+
+	msgs := make(chan Message)
+	var wg sync.WaitGroup
+	for _, q := range topics {
+		if err := DeclareConsumerQueue(ch, q); err != nil {
+			return err
+		}
+		deliveries, err := ch.Consume(q, q, false, false, false, false, nil)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(queue string) {
+			defer wg.Done()
+			for d := range deliveries {
+				select {
+				case msgs <- rabbitMessage(queue, d):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(q)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for _, q := range topics {
+				ch.Cancel(q, false)
+			}
+			wg.Wait()
+			return nil
+		case msg := <-msgs:
+			if err := handler(ctx, msg); err != nil {
+				slog.WarnContext(ctx, "event rejected", "topic", msg.Topic, "err", err)
+			}
+		}
+	}
+}
+
+// rabbitMessage wraps a delivery. A nacked message is requeued once; nacked
+// again after its redelivery it goes to the dead-letter queue.
+func rabbitMessage(queue string, d amqp.Delivery) Message {
+	key, _ := d.Headers["ordering_key"].(string)
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		if s, ok := v.(string); ok {
+			headers[k] = s
+		}
+	}
+	return Message{
+		Topic:   queue,
+		Key:     key,
+		Body:    d.Body,
+		Headers: headers,
+		Time:    d.Timestamp,
+		Ack:     func() { d.Ack(false) },
+		Nack: func() {
+			if d.Redelivered {
+				slog.Error("event dead-lettered", "queue", queue, "message_id", d.MessageId)
+				eventsDeadLettered.WithLabelValues(queue).Inc()
+			}
+			d.Nack(false, !d.Redelivered)
+		},
+	}
+}
+
+func (s *RabbitSubscriber) Close() {
+	if s.ch != nil {
+		s.ch.Close()
+	}
+	s.conn.Close()
+}
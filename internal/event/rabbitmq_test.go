@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The publisher is built without a connection: only the buffers are exercised.
+func TestRabbitPublishBuffers(t *testing.T) {
+	p := &RabbitPublisher{
+		opts:   RabbitOptions{EnqueueTimeout: 20 * time.Millisecond},
+		queues: []chan outgoing{make(chan outgoing, 2), make(chan outgoing, 2)},
+	}
+	ctx := context.Background()
+
+	// Events of one post share a buffer, in publish order
+	p.Publish(ctx, PostLiked{PostID: 7, UserID: 1})
+	p.Publish(ctx, PostUnliked{PostID: 7, UserID: 1})
+	q := p.queues[partition(PostLiked{PostID: 7}.OrderingKey(), 2)]
+	if len(q) != 2 {
+		t.Fatalf("buffer of post 7 holds %d events, want 2", len(q))
+	}
+	if first := <-q; first.typ != "PostLiked" {
+		t.Errorf("first event = %s", first.typ)
+	}
+	p.Publish(ctx, PostLiked{PostID: 7, UserID: 2})
+
+	// A full buffer drops the event after EnqueueTimeout without holding the lock
+	dropped := eventsFailed.WithLabelValues("PostLiked", "buffer_full")
+	before := testutil.ToFloat64(dropped)
+	done := make(chan struct{})
+	go func() {
+		p.Publish(ctx, PostLiked{PostID: 7, UserID: 3})
+		close(done)
+	}()
+	locked := make(chan struct{})
+	go func() {
+		time.Sleep(5 * time.Millisecond) // Publish is waiting by then
+		p.mu.Lock()
+		p.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Publish holds the lock while the buffer is full")
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocks past EnqueueTimeout")
+	}
+	if got := testutil.ToFloat64(dropped) - before; got != 1 {
+		t.Errorf("dropped = %v, want 1", got)
+	}
+
+	// After Close is requested nothing is enqueued
+	p.closed = true
+	p.Publish(ctx, PostLiked{PostID: 8, UserID: 1})
+	if n := len(p.queues[0]) + len(p.queues[1]); n != 2 {
+		t.Errorf("buffered = %d after close, want 2", n)
+	}
+}
+
+// nackRecorder is the acknowledger of a delivery without a channel.
+type nackRecorder struct{ requeued []bool }
+
+func (r *nackRecorder) Ack(uint64, bool) error { return nil }
+
+func (r *nackRecorder) Nack(_ uint64, _ bool, requeue bool) error {
+	r.requeued = append(r.requeued, requeue)
+	return nil
+}
+
+func (r *nackRecorder) Reject(uint64, bool) error { return nil }
+
+func TestRabbitNackDeadLettersRedeliveries(t *testing.T) {
+	rec := &nackRecorder{}
+	dead := eventsDeadLettered.WithLabelValues("comment_events")
+	before := testutil.ToFloat64(dead)
+
+	rabbitMessage("comment_events", amqp.Delivery{Acknowledger: rec}).Nack()
+	rabbitMessage("comment_events", amqp.Delivery{Acknowledger: rec, Redelivered: true}).Nack()
+
+	if want := []bool{true, false}; !slices.Equal(rec.requeued, want) {
+		t.Errorf("requeued = %v, want %v", rec.requeued, want)
+	}
+	if got := testutil.ToFloat64(dead) - before; got != 1 {
+		t.Errorf("dead-lettered = %v, want 1", got)
+	}
+}
@@ -0,0 +1,249 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// RedisPublisher appends events to a stream per partition: <prefix>:<n>, or
+// just <prefix> with a single partition.
+type RedisPublisher struct {
+	rdb        *redis.Client
+	prefix     string
+	partitions int
+}
+
+func NewRedisPublisher(rdb *redis.Client, prefix string, partitions int) *RedisPublisher {
+	return &RedisPublisher{rdb: rdb, prefix: prefix, partitions: partitions}
+}
+
+func (p *RedisPublisher) stream(key string) string {
+	if p.partitions <= 1 {
+		return p.prefix
+	}
+	return fmt.Sprintf("%s:%d", p.prefix, partition(key, p.partitions))
+}
+
+func (p *RedisPublisher) Publish(ctx context.Context, evt Event) {
+	p.PublishConfirmed(ctx, evt)
+}
+
+// PublishConfirmed returns once the entry is appended to its stream.
+func (p *RedisPublisher) PublishConfirmed(ctx context.Context, evt Event) error {
+	env, data, err := encode(ctx, evt)
+	if err != nil {
+		slog.ErrorContext(ctx, "event marshal failed", "event", env.Type, "err", err)
+		eventsFailed.WithLabelValues(env.Type, "encode").Inc()
+		return err
+	}
+	key := evt.OrderingKey()
+	stream := p.stream(key)
+	ctx, span, traceHeaders := startPublish(ctx, semconv.MessagingSystemKey.String("redis"), env, stream)
+	defer span.End()
+	values := map[string]any{
+		"type":        env.Type,
+		"routing_key": RoutingKey(evt),
+		"key":         key,
+		"body":        data,
+	}
+	// The trace context travels as extra fields next to the body
+	for k, v := range traceHeaders {
+		values[k] = v
+	}
+	eventsPublished.WithLabelValues(env.Type).Inc()
+	err = p.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream, Values: values}).Err()
+	if err != nil {
+		slog.ErrorContext(ctx, "event publish failed", "event", env.Type, "err", err)
+		span.SetStatus(codes.Error, err.Error())
+		eventsFailed.WithLabelValues(env.Type, "publish").Inc()
+		return err
+	}
+	eventsConfirmed.WithLabelValues(env.Type).Inc()
+	return nil
+}
+
+func (p *RedisPublisher) Close() {}
+
+type RedisSubscriber struct {
+	rdb       *redis.Client
+	group     string
+	consumer  string
+	prefetch  int
+	claimIdle time.Duration
+}
+
+// NewRedisSubscriber names the consumer after the host, so a restarted
+// instance finds its own pending entries. Entries pending for claimIdle, left
+// by a crashed instance or nacked, are claimed and delivered again.
+func NewRedisSubscriber(rdb *redis.Client, group string, prefetch int, claimIdle time.Duration) *RedisSubscriber {
+	consumer, err := os.Hostname()
+	if err != nil || consumer == "" {
+		consumer = fmt.Sprintf("%s-%d", group, time.Now().UnixNano())
+	}
+	if claimIdle <= 0 {
+		claimIdle = time.Minute
+	}
+	return &RedisSubscriber{
+		rdb:       rdb,
+		group:     group,
+		consumer:  consumer,
+		prefetch:  prefetch,
+		claimIdle: claimIdle,
+	}
+}
+
+// Subscribe reads the streams as a consumer group, prefetch entries at a time.
+// Pending entries are claimed at the start and then every claimIdle.
+func (s *RedisSubscriber) Subscribe(ctx context.Context, topics []string, handler Handler) error {
+	for _, t := range topics {
+		err := s.rdb.XGroupCreateMkStream(ctx, t, s.group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	streams := make([]string, 0, 2*len(topics))
+	streams = append(streams, topics...)
+	for range topics {
+		streams = append(streams, ">")
+	}
+
+	var claimed time.Time
+	for {
+		if time.Since(claimed) >= s.claimIdle {
+			s.claim(ctx, topics, handler)
+			claimed = time.Now()
+		}
+		res, err := s.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  streams,
+			Count:    int64(max(s.prefetch, 1)),
+			Block:    5 * time.Second,
+		}).Result()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "xreadgroup failed", "err", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, stream := range res {
+			for _, m := range stream.Messages {
+				s.deliver(ctx, stream.Stream, m, handler)
+			}
+		}
+	}
+}
+
+// claim takes over the entries of topics pending for claimIdle and delivers them.
+func (s *RedisSubscriber) claim(ctx context.Context, topics []string, handler Handler) {
+	for _, t := range topics {
+		start := "0-0"
+		for ctx.Err() == nil {
+			msgs, next, err := s.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   t,
+				Group:    s.group,
+				Consumer: s.consumer,
+				MinIdle:  s.claimIdle,
+				Start:    start,
+				Count:    int64(max(s.prefetch, 1)),
+			}).Result()
+			if err != nil {
+				slog.ErrorContext(ctx, "xautoclaim failed", "stream", t, "err", err)
+				break
+			}
+			if len(msgs) > 0 {
+				slog.InfoContext(ctx, "claimed pending entries", "stream", t, "count", len(msgs))
+			}
+			for _, m := range msgs {
+				if s.exhausted(ctx, t, m.ID) {
+					s.deadLetter(ctx, t, m)
+					continue
+				}
+				s.deliver(ctx, t, m, handler)
+			}
+			if next == "0-0" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// exhausted tells whether a claimed entry was delivered maxDeliveries times
+// before this claim.
+func (s *RedisSubscriber) exhausted(ctx context.Context, stream, id string) bool {
+	pending, err := s.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  s.group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	return err == nil && len(pending) == 1 && pending[0].RetryCount > maxDeliveries
+}
+
+// deadLetter moves an entry to <stream>:dead and acks it.
+func (s *RedisSubscriber) deadLetter(ctx context.Context, stream string, m redis.XMessage) {
+	err := s.rdb.XAdd(ctx, &redis.XAddArgs{Stream: stream + ":dead", Values: m.Values}).Err()
+	if err != nil {
+		slog.ErrorContext(ctx, "dead-letter failed", "stream", stream, "id", m.ID, "err", err)
+		return
+	}
+	s.rdb.XAck(ctx, stream, s.group, m.ID)
+	slog.ErrorContext(ctx, "event dead-lettered", "stream", stream, "id", m.ID)
+	eventsDeadLettered.WithLabelValues(stream).Inc()
+}
+
+// deliver hands an entry to handler. A nacked entry stays pending until claimed.
+func (s *RedisSubscriber) deliver(ctx context.Context, stream string, m redis.XMessage, handler Handler) {
+	body, _ := m.Values["body"].(string)
+	key, _ := m.Values["key"].(string)
+	headers := map[string]string{}
+	for _, f := range otel.GetTextMapPropagator().Fields() {
+		if v, ok := m.Values[f].(string); ok {
+			headers[f] = v
+		}
+	}
+	id := m.ID
+	msg := Message{
+		Topic:   stream,
+		Key:     key,
+		Body:    []byte(body),
+		Headers: headers,
+		Time:    streamIDTime(id),
+		Ack: func() {
+			s.rdb.XAck(context.Background(), stream, s.group, id)
+		},
+		Nack: func() {},
+	}
+	if err := handler(ctx, msg); err != nil {
+		slog.WarnContext(ctx, "event rejected", "topic", stream, "err", err)
+	}
+}
+
+// streamIDTime is the time of an entry, the milliseconds in the first part of its ID.
+func streamIDTime(id string) time.Time {
+	ms, _, _ := strings.Cut(id, "-")
+	n, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(n)
+}
+
+func (s *RedisSubscriber) Close() {}
@@ -0,0 +1,103 @@
+package event
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Committed schemas, one file per event type and version: <Type>.v<Version>.json.
+// They are regenerated with `go test ./internal/event -update`.
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// SchemaFileName is the name of the committed schema of evt.
+func SchemaFileName(evt Event) string {
+	return fmt.Sprintf("%s.v%d.json", evt.EventType(), evt.EventVersion())
+}
+
+// Schemas returns every committed schema by file name without the extension,
+// including older versions still consumed by other services.
+func Schemas() (map[string]json.RawMessage, error) {
+	entries, err := schemaFS.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]json.RawMessage, len(entries))
+	for _, e := range entries {
+		data, err := schemaFS.ReadFile(path.Join("schemas", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out[strings.TrimSuffix(e.Name(), ".json")] = data
+	}
+	return out, nil
+}
+
+// GenerateSchema builds the JSON Schema of the envelope of evt from the Go types.
+func GenerateSchema(evt Event) map[string]any {
+	s := typeSchema(reflect.TypeOf(Envelope{}))
+	props := s["properties"].(map[string]any)
+	props["id"] = map[string]any{"type": "string", "format": "uuid"}
+	props["type"] = map[string]any{"const": evt.EventType()}
+	props["version"] = map[string]any{"const": evt.EventVersion()}
+	props["payload"] = typeSchema(reflect.TypeOf(evt))
+
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["$id"] = "post-service/events/" + SchemaFileName(evt)
+	s["title"] = evt.EventType()
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func typeSchema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		return typeSchema(t.Elem())
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		props := map[string]any{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = typeSchema(f.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": props, "required": required}
+	}
+	return map[string]any{}
+}
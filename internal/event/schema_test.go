@@ -0,0 +1,152 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite committed event schemas after a compatible change")
+
+// TestEventSchemas is the contract test of outgoing events. A payload change that
+// consumers can absorb (a new field) only requires -update; removing a field,
+// changing its type or making it optional requires a new EventVersion.
+func TestEventSchemas(t *testing.T) {
+	for _, evt := range Registry {
+		t.Run(evt.EventType(), func(t *testing.T) {
+			file := filepath.Join("schemas", SchemaFileName(evt))
+			generated, err := json.MarshalIndent(GenerateSchema(evt), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			generated = append(generated, '\n')
+
+			committed, err := os.ReadFile(file)
+			if os.IsNotExist(err) {
+				if !*update {
+					t.Fatalf("%s is missing, run go test ./internal/event -update", file)
+				}
+				writeSchema(t, file, generated)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(committed, generated) {
+				return
+			}
+
+			var old, cur map[string]any
+			if err := json.Unmarshal(committed, &old); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(generated, &cur); err != nil {
+				t.Fatal(err)
+			}
+			if problems := incompatibilities("", old, cur); len(problems) > 0 {
+				t.Fatalf("incompatible change of %s, bump %s.EventVersion:\n%v", file, evt.EventType(), problems)
+			}
+			if !*update {
+				t.Fatalf("%s is outdated, run go test ./internal/event -update", file)
+			}
+			writeSchema(t, file, generated)
+		})
+	}
+}
+
+func TestIncompatibilities(t *testing.T) {
+	obj := func(props map[string]any, required ...string) map[string]any {
+		req := make([]any, len(required))
+		for i, r := range required {
+			req[i] = r
+		}
+		return map[string]any{"type": "object", "properties": props, "required": req}
+	}
+	str := map[string]any{"type": "string"}
+	num := map[string]any{"type": "integer"}
+
+	tests := []struct {
+		name     string
+		old, cur map[string]any
+		want     int
+	}{
+		{"same", obj(map[string]any{"a": str}, "a"), obj(map[string]any{"a": str}, "a"), 0},
+		{"added field", obj(map[string]any{"a": str}, "a"), obj(map[string]any{"a": str, "b": num}, "a", "b"), 0},
+		{"removed field", obj(map[string]any{"a": str, "b": num}, "a", "b"), obj(map[string]any{"a": str}, "a"), 2},
+		{"changed type", obj(map[string]any{"a": str}, "a"), obj(map[string]any{"a": num}, "a"), 1},
+		{"made optional", obj(map[string]any{"a": str}, "a"), obj(map[string]any{"a": str}), 1},
+		{"nested", obj(map[string]any{"p": obj(map[string]any{"a": str}, "a")}, "p"),
+			obj(map[string]any{"p": obj(map[string]any{"a": num}, "a")}, "p"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Round trip through JSON as the committed files are
+			old, cur := roundTrip(t, tt.old), roundTrip(t, tt.cur)
+			if got := incompatibilities("", old, cur); len(got) != tt.want {
+				t.Fatalf("got %v, want %d problems", got, tt.want)
+			}
+		})
+	}
+}
+
+// incompatibilities lists the changes from old to cur that break existing consumers.
+func incompatibilities(path string, old, cur map[string]any) []string {
+	var problems []string
+	for _, key := range []string{"type", "format", "const"} {
+		if !reflect.DeepEqual(old[key], cur[key]) {
+			problems = append(problems, fmt.Sprintf("%s: %s changed from %v to %v", path, key, old[key], cur[key]))
+		}
+	}
+
+	oldProps, _ := old["properties"].(map[string]any)
+	curProps, _ := cur["properties"].(map[string]any)
+	for name, o := range oldProps {
+		c, ok := curProps[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s: removed", path, name))
+			continue
+		}
+		problems = append(problems, incompatibilities(path+"."+name, o.(map[string]any), c.(map[string]any))...)
+	}
+
+	curRequired, _ := cur["required"].([]any)
+	oldRequired, _ := old["required"].([]any)
+	for _, r := range oldRequired {
+		if !slices.Contains(curRequired, r) {
+			problems = append(problems, fmt.Sprintf("%s.%v: no longer required", path, r))
+		}
+	}
+
+	if o, ok := old["items"].(map[string]any); ok {
+		if c, ok := cur["items"].(map[string]any); ok {
+			problems = append(problems, incompatibilities(path+"[]", o, c)...)
+		}
+	}
+	return problems
+}
+
+func roundTrip(t *testing.T, v map[string]any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func writeSchema(t *testing.T, file string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
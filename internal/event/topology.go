@@ -0,0 +1,104 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Topology is the RabbitMQ layout of outgoing events: a durable topic exchange
+// and the queues of the consumer services bound to it by routing key patterns.
+type Topology struct {
+	Exchange string
+	Bindings []QueueBinding
+}
+
+type QueueBinding struct {
+	Queue string
+	Keys  []string
+}
+
+// ParseBindings reads "queue=key1,key2;queue2=key3", e.g.
+// "notification_post_events=post.created,post.liked;search_post_events=post.#".
+func ParseBindings(s string) ([]QueueBinding, error) {
+	var bindings []QueueBinding
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		queue, keys, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(queue) == "" {
+			return nil, fmt.Errorf("invalid queue binding %q", part)
+		}
+		b := QueueBinding{Queue: strings.TrimSpace(queue)}
+		for _, k := range strings.Split(keys, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				b.Keys = append(b.Keys, k)
+			}
+		}
+		if len(b.Keys) == 0 {
+			return nil, fmt.Errorf("queue %q has no routing keys", b.Queue)
+		}
+		bindings = append(bindings, b)
+	}
+	return bindings, nil
+}
+
+// Declare creates the exchange, the queues and their bindings; it is idempotent.
+func (t Topology) Declare(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(t.Exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare exchange %s: %w", t.Exchange, err)
+	}
+	for _, b := range t.Bindings {
+		if _, err := ch.QueueDeclare(b.Queue, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("declare queue %s: %w", b.Queue, err)
+		}
+		for _, key := range b.Keys {
+			if err := ch.QueueBind(b.Queue, key, t.Exchange, false, nil); err != nil {
+				return fmt.Errorf("bind %s to %s: %w", b.Queue, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// DeclareConsumerQueue creates a queue of incoming events with its dead-letter
+// queue, <queue>.dead: a message rejected without requeue is moved there
+// through the default exchange instead of being lost.
+func DeclareConsumerQueue(ch *amqp.Channel, queue string) error {
+	dead := queue + ".dead"
+	if _, err := ch.QueueDeclare(dead, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("declare queue %s: %w", dead, err)
+	}
+	args := amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": dead,
+	}
+	if _, err := ch.QueueDeclare(queue, true, false, false, false, args); err != nil {
+		return fmt.Errorf("declare queue %s: %w", queue, err)
+	}
+	return nil
+}
+
+// RoutingKey maps an event type to its key: PostCreated -> post.created.
+func RoutingKey(evt Event) string {
+	if r, ok := evt.(Rerouted); ok {
+		return r.Key
+	}
+	name := strings.TrimPrefix(evt.EventType(), "Post")
+	return "post." + strings.ToLower(name)
+}
+
+// Rerouted publishes an event under another routing key, e.g. replays aimed at
+// a single consumer. The payload is the one of the wrapped event.
+type Rerouted struct {
+	Event
+	Key string
+}
+
+func (r Rerouted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Event)
+}
@@ -0,0 +1,42 @@
+package event
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("post-service/internal/event")
+
+// startPublish starts the producer span of an outgoing event; the headers
+// returned carry its trace context to the consumers.
+func startPublish(ctx context.Context, system attribute.KeyValue, env Envelope, destination string) (context.Context, trace.Span, map[string]string) {
+	ctx, span := tracer.Start(ctx, "publish "+env.Type,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			system,
+			semconv.MessagingOperationTypeSend,
+			semconv.MessagingDestinationName(destination),
+			semconv.MessagingMessageID(env.ID),
+		),
+	)
+	headers := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+	return ctx, span, headers
+}
+
+// startConsume continues the trace found in the headers of an incoming message.
+func startConsume(ctx context.Context, msg Message, eventType string) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(msg.Headers))
+	return tracer.Start(ctx, "process "+eventType,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingOperationTypeProcess,
+			semconv.MessagingDestinationName(msg.Topic),
+		),
+	)
+}
@@ -0,0 +1,79 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracePropagation(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+		tp.Shutdown(context.Background())
+	})
+
+	ctx, request := tp.Tracer("test").Start(context.Background(), "POST /posts/:id/like")
+	bus := NewMemoryBus()
+	bus.Publish(ctx, PostLiked{PostID: 1, UserID: 2})
+	request.End()
+	traceID := request.SpanContext().TraceID()
+
+	msg := bus.Published()[0]
+	if msg.Headers["traceparent"] == "" {
+		t.Fatalf("no traceparent header: %v", msg.Headers)
+	}
+	var env struct {
+		TraceID string `json:"traceId"`
+	}
+	if err := json.Unmarshal(msg.Body, &env); err != nil || env.TraceID != traceID.String() {
+		t.Errorf("envelope traceId = %q, want %s (%v)", env.TraceID, traceID, err)
+	}
+
+	c, err := NewConsumer(bus, newMemRepo(), 1, UserDeletedAnonymize, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.handle(context.Background(), msg, incomingEvent{Event: "PostLiked", PostID: 1})
+
+	var names []string
+	for _, s := range rec.Ended() {
+		names = append(names, s.Name())
+		if s.SpanContext().TraceID() != traceID {
+			t.Errorf("span %q is in trace %s, want %s", s.Name(), s.SpanContext().TraceID(), traceID)
+		}
+	}
+	want := []string{"publish PostLiked", "POST /posts/:id/like", "process PostLiked"}
+	if !slices.Equal(names, want) {
+		t.Errorf("spans = %v, want %v", names, want)
+	}
+}
+
+func TestTraceIDFromContext(t *testing.T) {
+	ctx := ContextWithTraceID(context.Background(), "request-1")
+	if got := TraceIDFromContext(ctx); got != "request-1" {
+		t.Errorf("untraced = %q, want the request ID", got)
+	}
+
+	tp := sdktrace.NewTracerProvider()
+	defer tp.Shutdown(context.Background())
+	ctx, span := tp.Tracer("test").Start(ctx, "request")
+	defer span.End()
+	if got := TraceIDFromContext(ctx); got != span.SpanContext().TraceID().String() {
+		t.Errorf("traced = %q, want the trace ID %s", got, span.SpanContext().TraceID())
+	}
+	if got := TraceIDFromContext(context.Background()); got != "" {
+		t.Errorf("empty context = %q", got)
+	}
+}
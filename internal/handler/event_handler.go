@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"post-service/internal/event"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EventSchemas lists the JSON Schemas of outgoing events by <Type>.v<Version>.
+func EventSchemas(c *fiber.Ctx) error {
+	schemas, err := event.Schemas()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(schemas)
+}
+
+func EventSchema(c *fiber.Ctx) error {
+	schemas, err := event.Schemas()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	schema, ok := schemas[c.Params("name")]
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "schema not found"})
+	}
+	c.Type("json")
+	return c.Send(schema)
+}
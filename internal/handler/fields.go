@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sparseFields applies the fields= query parameter (e.g. fields=id,title,likesCount)
+// to a list of items: only the listed JSON keys are kept. Unknown names are ignored.
+func sparseFields(c *fiber.Ctx, items any) (any, error) {
+	raw := c.Query("fields")
+	if raw == "" {
+		return items, nil
+	}
+	keep := make(map[string]bool)
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			keep[f] = true
+		}
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		for k := range obj {
+			if !keep[k] {
+				delete(obj, k)
+			}
+		}
+	}
+	return objects, nil
+}
@@ -1,8 +1,9 @@
 package handler
 
 import (
-	"log"
+	"log/slog"
 	"post-service/internal/dto"
+	"post-service/internal/logging"
 	"post-service/internal/middleware"
 	"post-service/internal/service"
 	"strconv"
@@ -28,23 +29,24 @@ func (h *PostHandler) Create(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
-	log.Printf("CreatePost: title=%q, content_len=%d, author_id=%d", req.Title, len(req.Content), req.AuthorID)
 	if err := h.validate.Struct(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	userID := middleware.GetUserID(c)
-	log.Printf("CreatePost: userID from header=%d", userID)
 	if req.AuthorID == 0 {
 		req.AuthorID = userID
 	}
 	username := c.Get("X-Username")
 	avatarURL := c.Get("X-User-Avatar")
 
-	post, err := h.svc.Create(c.Context(), userID, username, avatarURL, &req)
+	post, err := h.svc.Create(c.UserContext(), userID, username, avatarURL, &req)
 	if err != nil {
+		slog.ErrorContext(c.UserContext(), "create post", "err", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	logging.SetPostID(c.UserContext(), post.ID)
+	slog.DebugContext(c.UserContext(), "post created", "content_len", len(req.Content), "status", post.Status)
 	return c.Status(fiber.StatusCreated).JSON(post)
 }
 
@@ -55,34 +57,40 @@ func (h *PostHandler) GetPost(c *fiber.Ctx) error {
 	}
 	userID := middleware.GetUserID(c)
 
-	post, err := h.svc.GetPost(c.Context(), id, userID)
+	post, err := h.svc.GetPost(c.UserContext(), id, userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
 	}
 	if userID != 0 {
-		_ = h.svc.IncrementView(c.Context(), id, userID)
+		_ = h.svc.IncrementView(c.UserContext(), id, userID)
 	}
 	return c.JSON(post)
 }
 
 func (h *PostHandler) ListPosts(c *fiber.Ctx) error {
 	q := dto.ListPostsQuery{
-		Limit:  c.QueryInt("limit", 20),
-		Offset: c.QueryInt("offset", 0),
-		Sort:   c.Query("sort", "new"),
-		Author: c.Query("author"),
-		Tag:    c.Query("tag"),
+		Limit:           c.QueryInt("limit", 20),
+		Offset:          c.QueryInt("offset", 0),
+		Sort:            c.Query("sort", "new"),
+		Period:          c.Query("period", "all"),
+		Author:          c.Query("author"),
+		Tag:             c.Query("tag"),
+		IncludeArchived: c.QueryBool("includeArchived"),
 	}
 	if q.Limit < 1 || q.Limit > 100 {
 		q.Limit = 20
 	}
 
 	userID := middleware.GetUserID(c)
-	posts, err := h.svc.ListPosts(c.Context(), q, userID)
+	posts, err := h.svc.ListPosts(c.UserContext(), q, userID)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	items, err := sparseFields(c, posts)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(posts)
+	return c.JSON(items)
 }
 
 func (h *PostHandler) HotPosts(c *fiber.Ctx) error {
@@ -103,13 +111,62 @@ func (h *PostHandler) Search(c *fiber.Ctx) error {
 		limit = 20
 	}
 
-	posts, err := h.svc.SearchPosts(c.Context(), q, limit, offset)
+	userID := middleware.GetUserID(c)
+	posts, err := h.svc.SearchPosts(c.UserContext(), q, limit, offset, c.QueryBool("includeArchived"), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	items, err := sparseFields(c, posts)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(items)
+}
+
+func (h *PostHandler) AuthorPosts(c *fiber.Ctx) error {
+	authorID, err := strconv.ParseInt(c.Params("authorId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid author id"})
+	}
+	return h.listAuthorPosts(c, authorID)
+}
+
+func (h *PostHandler) MyPosts(c *fiber.Ctx) error {
+	return h.listAuthorPosts(c, middleware.GetUserID(c))
+}
+
+func (h *PostHandler) listAuthorPosts(c *fiber.Ctx, authorID int64) error {
+	q := dto.ListAuthorPostsQuery{
+		Limit:           c.QueryInt("limit", 20),
+		Offset:          c.QueryInt("offset", 0),
+		Status:          c.Query("status"),
+		IncludeArchived: c.QueryBool("includeArchived"),
+	}
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
+	}
+
+	userID := middleware.GetUserID(c)
+	posts, err := h.svc.ListAuthorPosts(c.UserContext(), authorID, userID, q)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
 	return c.JSON(posts)
 }
 
+func (h *PostHandler) AuthorStats(c *fiber.Ctx) error {
+	authorID, err := strconv.ParseInt(c.Params("authorId"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid author id"})
+	}
+
+	stats, err := h.svc.GetAuthorStats(c.UserContext(), authorID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(stats)
+}
+
 func (h *PostHandler) Update(c *fiber.Ctx) error {
 	id, err := parseID(c)
 	if err != nil {
@@ -119,9 +176,12 @@ func (h *PostHandler) Update(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
 	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 	userID := middleware.GetUserID(c)
 
-	post, err := h.svc.UpdatePost(c.Context(), id, userID, &req)
+	post, err := h.svc.UpdatePost(c.UserContext(), id, userID, &req)
 	if err != nil {
 		return handleServiceError(c, err)
 	}
@@ -135,7 +195,7 @@ func (h *PostHandler) Delete(c *fiber.Ctx) error {
 	}
 	userID := middleware.GetUserID(c)
 
-	if err := h.svc.DeletePost(c.Context(), id, userID); err != nil {
+	if err := h.svc.DeletePost(c.UserContext(), id, userID); err != nil {
 		return handleServiceError(c, err)
 	}
 	return c.SendStatus(fiber.StatusNoContent)
@@ -148,11 +208,11 @@ func (h *PostHandler) Like(c *fiber.Ctx) error {
 	}
 	userID := middleware.GetUserID(c)
 
-	if err := h.svc.Like(c.Context(), id, userID); err != nil {
+	if err := h.svc.Like(c.UserContext(), id, userID); err != nil {
 		if err.Error() == "already liked" {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "already liked"})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return handleServiceError(c, err)
 	}
 	return c.JSON(fiber.Map{"action": "liked"})
 }
@@ -164,27 +224,148 @@ func (h *PostHandler) Unlike(c *fiber.Ctx) error {
 	}
 	userID := middleware.GetUserID(c)
 
-	if err := h.svc.Unlike(c.Context(), id, userID); err != nil {
+	if err := h.svc.Unlike(c.UserContext(), id, userID); err != nil {
 		if err.Error() == "not liked" {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "not liked"})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return handleServiceError(c, err)
 	}
 	return c.JSON(fiber.Map{"action": "unliked"})
 }
 
+func (h *PostHandler) MyLikes(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	userID := middleware.GetUserID(c)
+
+	page, err := h.svc.ListLikedPosts(c.UserContext(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	return c.JSON(page)
+}
+
+func (h *PostHandler) PostLikers(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	resp, err := h.svc.ListPostLikers(c.UserContext(), id, middleware.GetUserID(c), c.Query("cursor"), limit)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	return c.JSON(resp)
+}
+
+func (h *PostHandler) Bookmark(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	var req dto.BookmarkRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+		}
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	userID := middleware.GetUserID(c)
+
+	if err := h.svc.Bookmark(c.UserContext(), id, userID, req.Folder); err != nil {
+		return handleServiceError(c, err)
+	}
+	return c.JSON(fiber.Map{"action": "bookmarked", "folder": req.Folder})
+}
+
+func (h *PostHandler) Unbookmark(c *fiber.Ctx) error {
+	id, err := parseID(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	userID := middleware.GetUserID(c)
+
+	if err := h.svc.Unbookmark(c.UserContext(), id, userID); err != nil {
+		if err.Error() == "not bookmarked" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "not bookmarked"})
+		}
+		return handleServiceError(c, err)
+	}
+	return c.JSON(fiber.Map{"action": "unbookmarked"})
+}
+
+func (h *PostHandler) MyBookmarks(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	// An absent folder lists all bookmarks, folder= lists the unfiled ones
+	var folder *string
+	if c.Request().URI().QueryArgs().Has("folder") {
+		f := c.Query("folder")
+		folder = &f
+	}
+	userID := middleware.GetUserID(c)
+
+	page, err := h.svc.ListBookmarks(c.UserContext(), userID, folder, c.Query("cursor"), limit)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	return c.JSON(page)
+}
+
+func (h *PostHandler) Feed(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	userID := middleware.GetUserID(c)
+
+	page, err := h.svc.Feed(c.UserContext(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		return handleServiceError(c, err)
+	}
+	items, err := sparseFields(c, page.Items)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	resp := fiber.Map{"items": items}
+	if page.NextCursor != "" {
+		resp["nextCursor"] = page.NextCursor
+	}
+	return c.JSON(resp)
+}
+
 // --- Хелперы ---
 
+// parseID reads the post id of the route and adds it to the log fields.
 func parseID(c *fiber.Ctx) (int64, error) {
-	return strconv.ParseInt(c.Params("id"), 10, 64)
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err == nil {
+		logging.SetPostID(c.UserContext(), id)
+	}
+	return id, err
 }
 
 func handleServiceError(c *fiber.Ctx, err error) error {
 	switch err.Error() {
 	case "forbidden":
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "forbidden"})
+	case "post is archived", "post is hidden", "post is published":
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
 	case "post not found":
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+	case "invalid status", "invalid cursor", "invalid period", "invalid content format":
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	slog.ErrorContext(c.UserContext(), "request failed", "err", err)
 	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 }
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"path/filepath"
+	"post-service/internal/middleware"
+	"post-service/internal/service"
+	"post-service/internal/storage"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UploadHandler struct {
+	svc   service.UploadService
+	local *storage.Local // nil unless files are served by this service
+}
+
+func NewUploadHandler(svc service.UploadService, blob storage.Blob) *UploadHandler {
+	local, _ := blob.(*storage.Local)
+	return &UploadHandler{svc: svc, local: local}
+}
+
+func (h *UploadHandler) Upload(c *fiber.Ctx) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing file"})
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid file"})
+	}
+	defer f.Close()
+
+	resp, err := h.svc.Upload(c.UserContext(), middleware.GetUserID(c), f)
+	if err != nil {
+		switch err.Error() {
+		case "file too large":
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+		case "unsupported media type":
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// Download serves blobs of the local backend by signed URL.
+func (h *UploadHandler) Download(c *fiber.Ctx) error {
+	if h.local == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	key := "uploads/" + c.Params("*")
+	if !h.local.Verify(key, c.Query("expires"), c.Query("sig")) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invalid or expired signature"})
+	}
+	f, err := h.local.Open(key)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	c.Type(strings.TrimPrefix(filepath.Ext(key), "."))
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return c.SendStream(f, int(st.Size()))
+}
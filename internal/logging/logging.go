@@ -0,0 +1,152 @@
+// Package logging sets up the JSON slog logger and the request-scoped fields
+// (request, route, user, post and trace) that every record logged with a
+// context carries.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New returns a JSON logger writing records at level and above; an unknown
+// level means info.
+func New(w io.Writer, level string) *slog.Logger {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact})
+	return slog.New(contextHandler{h})
+}
+
+type fieldsKey struct{}
+
+// fields are filled in as the request goes through the middlewares and the
+// handler, so they are read when a record is written, not when ctx is made.
+type fields struct {
+	mu        sync.Mutex
+	requestID string
+	route     func() string
+	userID    int64
+	postID    int64
+}
+
+// NewContext starts the fields of one request or incoming event.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, &fields{requestID: requestID})
+}
+
+func fieldsFrom(ctx context.Context) *fields {
+	f, _ := ctx.Value(fieldsKey{}).(*fields)
+	return f
+}
+
+// SetRoute sets the route; route is called for every record.
+func SetRoute(ctx context.Context, route func() string) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.route = route
+		f.mu.Unlock()
+	}
+}
+
+func SetUserID(ctx context.Context, id int64) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.userID = id
+		f.mu.Unlock()
+	}
+}
+
+func SetPostID(ctx context.Context, id int64) {
+	if f := fieldsFrom(ctx); f != nil {
+		f.mu.Lock()
+		f.postID = id
+		f.mu.Unlock()
+	}
+}
+
+func (f *fields) attrs() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var attrs []slog.Attr
+	if f.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", f.requestID))
+	}
+	if f.route != nil {
+		attrs = append(attrs, slog.String("route", f.route()))
+	}
+	if f.userID != 0 {
+		attrs = append(attrs, slog.Int64("user_id", f.userID))
+	}
+	if f.postID != 0 {
+		attrs = append(attrs, slog.Int64("post_id", f.postID))
+	}
+	return attrs
+}
+
+// contextHandler adds the fields and the trace of the record's context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if f := fieldsFrom(ctx); f != nil {
+		r.AddAttrs(f.attrs()...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+const redacted = "[REDACTED]"
+
+// Attributes whose key contains one of these are never written
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie", "api_key", "access_key", "signing_key"}
+
+// redact hides sensitive attributes and the passwords of URLs in strings,
+// the message included.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	if a.Value.Kind() == slog.KindString {
+		if s := a.Value.String(); strings.Contains(s, "://") {
+			return slog.String(a.Key, redactURLs(s))
+		}
+	}
+	return a
+}
+
+// redactURLs replaces the password of every URL in s.
+func redactURLs(s string) string {
+	for _, w := range strings.Fields(s) {
+		raw := strings.Trim(w, `"'(),;`)
+		u, err := url.Parse(raw)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			s = strings.ReplaceAll(s, raw, u.Redacted())
+		}
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimit rejects request bodies larger than limit, unless skip returns true.
+// The server streams request bodies, so the declared length is checked before
+// the body is read, and a chunked body is read up to limit.
+func BodyLimit(limit int, skip func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if skip != nil && skip(c) {
+			return c.Next()
+		}
+		switch n := c.Request().Header.ContentLength(); {
+		case n > limit:
+			return bodyTooLarge(c)
+		case n == -1:
+			stream := c.Context().RequestBodyStream()
+			if stream == nil {
+				break
+			}
+			body, err := io.ReadAll(io.LimitReader(stream, int64(limit)+1))
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+			}
+			if len(body) > limit {
+				return bodyTooLarge(c)
+			}
+			c.Request().SetBody(body)
+		}
+		return c.Next()
+	}
+}
+
+// bodyTooLarge also closes the connection: the rest of the body is never read.
+func bodyTooLarge(c *fiber.Ctx) error {
+	c.Context().SetConnectionClose()
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "request body too large"})
+}
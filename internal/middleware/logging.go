@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log/slog"
+	"post-service/internal/event"
+	"post-service/internal/logging"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequestLogger gives every request an ID (X-Request-ID, generated when the
+// gateway did not send one) and the logging fields of its context, and logs
+// the request when it is done. Handlers must pass c.UserContext() on.
+func RequestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Set(fiber.HeaderXRequestID, requestID)
+
+		ctx := logging.NewContext(c.UserContext(), requestID)
+		// The route is known once the router has matched the handler
+		logging.SetRoute(ctx, func() string { return c.Route().Path })
+		if userID, err := strconv.ParseInt(c.Get("X-User-ID"), 10, 64); err == nil {
+			logging.SetUserID(ctx, userID)
+		}
+		// Events of an untraced request still point back to it
+		c.SetUserContext(event.ContextWithTraceID(ctx, requestID))
+
+		err := c.Next()
+		if err != nil {
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		// c is reused after the request, keep the route as a value
+		route := c.Route().Path
+		logging.SetRoute(ctx, func() string { return route })
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(ctx, level, "request",
+			"method", c.Method(),
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+
+	excerptLength = 280
+)
+
+// Content is a post body in all stored forms.
+type Content struct {
+	Format  string
+	Source  string
+	HTML    string // sanitized, safe to insert into a page as is
+	Text    string // plain text, indexed by full-text search
+	Excerpt string
+}
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// Allow-list of user generated markup: formatting, links, images, lists, code, tables.
+	htmlPolicy = func() *bluemonday.Policy {
+		p := bluemonday.UGCPolicy()
+		p.RequireNoFollowOnLinks(true)
+		p.AddTargetBlankToFullyQualifiedLinks(true)
+		p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+		return p
+	}()
+
+	textPolicy = bluemonday.StrictPolicy()
+)
+
+func Render(format, source string) (Content, error) {
+	c := Content{Format: format, Source: source}
+	switch format {
+	case "", FormatPlain:
+		c.Format = FormatPlain
+		c.HTML = plainToHTML(source)
+		c.Text = source
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(source), &buf); err != nil {
+			return Content{}, err
+		}
+		c.HTML = htmlPolicy.Sanitize(buf.String())
+		c.Text = htmlToText(c.HTML)
+	default:
+		return Content{}, fmt.Errorf("invalid content format")
+	}
+	c.Excerpt = Excerpt(c.Text, excerptLength)
+	return c, nil
+}
+
+// Excerpt cuts text to at most n runes on a word boundary, whitespace collapsed.
+func Excerpt(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	cut := n
+	for cut > n/2 && !unicode.IsSpace(runes[cut]) {
+		cut--
+	}
+	if cut <= n/2 {
+		cut = n
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "…"
+}
+
+func plainToHTML(source string) string {
+	var b strings.Builder
+	for _, para := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n\n") {
+		para = strings.Trim(para, "\n")
+		if para == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+func htmlToText(s string) string {
+	// Keep block boundaries as whitespace so that words do not stick together
+	s = strings.NewReplacer("</p>", "</p>\n", "<br>", "\n", "</li>", "</li>\n", "</h1>", "</h1>\n",
+		"</h2>", "</h2>\n", "</h3>", "</h3>\n", "</pre>", "</pre>\n").Replace(s)
+	return strings.TrimSpace(html.UnescapeString(textPolicy.Sanitize(s)))
+}
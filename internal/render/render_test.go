@@ -0,0 +1,148 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		source  string
+		want    []string // substrings of the HTML
+		notWant []string
+	}{
+		{
+			name:    "plain text is escaped",
+			format:  FormatPlain,
+			source:  `<script>alert(1)</script> & "quotes"`,
+			want:    []string{"<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; &#34;quotes&#34;</p>"},
+			notWant: []string{"<script"},
+		},
+		{
+			name:    "plain paragraphs and line breaks",
+			format:  FormatPlain,
+			source:  "one\ntwo\r\n\r\nthree",
+			want:    []string{"<p>one<br>two</p>", "<p>three</p>"},
+			notWant: []string{"\r"},
+		},
+		{
+			name:    "raw script in markdown",
+			format:  FormatMarkdown,
+			source:  "hello\n\n<script>alert(1)</script>",
+			want:    []string{"<p>hello</p>"},
+			notWant: []string{"<script", "alert(1)"},
+		},
+		{
+			name:    "inline raw html in markdown",
+			format:  FormatMarkdown,
+			source:  `text <img src="x" onerror="alert(1)"> <b onclick="x()">bold</b>`,
+			notWant: []string{"onerror", "onclick", "<img", "<b"},
+		},
+		{
+			name:    "javascript link",
+			format:  FormatMarkdown,
+			source:  "[click](javascript:alert(1))",
+			notWant: []string{"javascript:", "href"},
+		},
+		{
+			name:    "data link",
+			format:  FormatMarkdown,
+			source:  "[click](data:text/html;base64,PHNjcmlwdD4=)",
+			notWant: []string{"data:", "href"},
+		},
+		{
+			name:   "external links get nofollow and a new tab",
+			format: FormatMarkdown,
+			source: "[site](https://example.com)",
+			want:   []string{`href="https://example.com"`, `rel="nofollow noopener"`, `target="_blank"`},
+		},
+		{
+			name:   "allowed formatting survives",
+			format: FormatMarkdown,
+			source: "# Title\n\n**bold** `code`\n\n```go\nx := 1\n```\n\n| a |\n|---|\n| b |",
+			want:   []string{"<h1>Title</h1>", "<strong>bold</strong>", "<code>code</code>", `<code class="language-go">`, "<table>"},
+		},
+		{
+			name:    "event handler attributes on allowed elements",
+			format:  FormatMarkdown,
+			source:  `![alt](https://example.com/a.png "t")` + "\n\n" + `<a href="https://example.com" onmouseover="x()">a</a>`,
+			want:    []string{`<img src="https://example.com/a.png" alt="alt"`},
+			notWant: []string{"onmouseover"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Render(tt.format, tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(c.HTML, s) {
+					t.Errorf("HTML %q does not contain %q", c.HTML, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(c.HTML, s) {
+					t.Errorf("HTML %q contains %q", c.HTML, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	c, err := Render(FormatMarkdown, "# Title\n\nFirst *para*.\n\n- one\n- two &amp; three")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Text != "Title\n\nFirst para.\n\n\none\n\ntwo & three" {
+		t.Errorf("Text = %q", c.Text)
+	}
+	if c.Excerpt != "Title First para. one two & three" {
+		t.Errorf("Excerpt = %q", c.Excerpt)
+	}
+
+	if _, err := Render("html", "x"); err == nil || err.Error() != "invalid content format" {
+		t.Errorf("unknown format: %v", err)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		n    int
+		want string
+	}{
+		{"short text is kept", "  a   b\n c ", 10, "a b c"},
+		{"cut on a word boundary", "hello wonderful world", 17, "hello wonderful…"},
+		{"no boundary past the middle", "hello wonderful world", 12, "hello wonder…"},
+		{"long word is cut inside", "abcdefghijklmnop", 5, "abcde…"},
+		{"multi-byte words", "привет большой мир", 16, "привет большой…"},
+		{"multi-byte runes are not split", strings.Repeat("é", 20), 7, strings.Repeat("é", 7) + "…"},
+		{"emoji", strings.Repeat("😀", 4), 3, "😀😀😀…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Excerpt(tt.text, tt.n)
+			if got != tt.want {
+				t.Errorf("Excerpt = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Excerpt %q is not valid UTF-8", got)
+			}
+		})
+	}
+
+	long := strings.Repeat("слово ", 100)
+	c, err := Render(FormatPlain, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := utf8.RuneCountInString(c.Excerpt); n > excerptLength+1 {
+		t.Errorf("excerpt of %d runes, want at most %d", n, excerptLength+1)
+	}
+}
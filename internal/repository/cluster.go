@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type primaryKey struct{}
+
+// WithPrimary makes the reads under ctx go to the primary, for flows that
+// must see their own writes despite replication lag.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func usePrimary(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryKey{}).(bool)
+	return forced
+}
+
+// Cluster is the primary with optional read replicas. Reads are spread
+// round-robin over the replicas that passed their last health check (see
+// CheckReplicas) and fall back to the primary when none did.
+type Cluster struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+func NewCluster(primary *pgxpool.Pool, replicas ...*pgxpool.Pool) *Cluster {
+	c := &Cluster{primary: primary}
+	for _, pool := range replicas {
+		c.replicas = append(c.replicas, &replica{pool: pool})
+	}
+	return c
+}
+
+func (c *Cluster) Primary() *pgxpool.Pool {
+	return c.primary
+}
+
+// Reader picks the pool for a read.
+func (c *Cluster) Reader(ctx context.Context) *pgxpool.Pool {
+	if len(c.replicas) == 0 || usePrimary(ctx) {
+		return c.primary
+	}
+	start := c.next.Add(1)
+	for i := range c.replicas {
+		r := c.replicas[(start+uint64(i))%uint64(len(c.replicas))]
+		if r.healthy.Load() {
+			return r.pool
+		}
+	}
+	return c.primary
+}
+
+// CheckReplicas pings every replica each interval until ctx is done. Replicas
+// take reads only after their first successful ping.
+func (c *Cluster) CheckReplicas(ctx context.Context, interval time.Duration) {
+	if len(c.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for i, r := range c.replicas {
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			err := r.pool.Ping(pingCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if healthy := err == nil; r.healthy.Swap(healthy) != healthy {
+				if healthy {
+					slog.InfoContext(ctx, "replica is back", "replica", i)
+				} else {
+					slog.WarnContext(ctx, "replica is down", "replica", i, "err", err)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the replicas and the primary.
+func (c *Cluster) Close() {
+	for _, r := range c.replicas {
+		r.pool.Close()
+	}
+	c.primary.Close()
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lazyPool does not connect until used, which Reader never does.
+func lazyPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestClusterReader(t *testing.T) {
+	primary, r1, r2 := lazyPool(t), lazyPool(t), lazyPool(t)
+	c := NewCluster(primary, r1, r2)
+	ctx := context.Background()
+
+	if got := c.Reader(ctx); got != primary {
+		t.Errorf("unchecked replicas took a read")
+	}
+
+	c.replicas[0].healthy.Store(true)
+	c.replicas[1].healthy.Store(true)
+	seen := map[*pgxpool.Pool]int{}
+	for range 4 {
+		seen[c.Reader(ctx)]++
+	}
+	if seen[r1] != 2 || seen[r2] != 2 {
+		t.Errorf("reads are not round-robin: r1=%d r2=%d primary=%d", seen[r1], seen[r2], seen[primary])
+	}
+
+	c.replicas[0].healthy.Store(false)
+	for range 3 {
+		if got := c.Reader(ctx); got != r2 {
+			t.Fatalf("read went past the only healthy replica")
+		}
+	}
+
+	if got := c.Reader(WithPrimary(ctx)); got != primary {
+		t.Errorf("WithPrimary read went to a replica")
+	}
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "post_service_db_query_duration_seconds",
+		Help:    "Duration of database queries by repository query.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"method"})
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_db_query_errors_total",
+		Help: "Database queries that returned an error, timeouts included.",
+	}, []string{"method"})
+)
@@ -2,272 +2,741 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"post-service/internal/domain"
-	"strings"
+	"post-service/internal/render"
+	"post-service/internal/repository/db"
+	"post-service/internal/util"
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+//go:generate sqlc generate -f ../../sqlc.yaml
+
 type PostRepository interface {
 	CreatePost(ctx context.Context, post *domain.Post) (int64, error)
 	GetPost(ctx context.Context, id int64) (*domain.Post, error)
-	UpdatePost(ctx context.Context, id int64, title *string, content *string, tags []string) (*domain.Post, error)
+	UpdatePost(ctx context.Context, id int64, upd PostUpdate) (*domain.Post, error)
 	DeletePost(ctx context.Context, id int64) error
-	ListPostsFiltered(ctx context.Context, limit, offset int, sort, author, tag string) ([]domain.Post, error)
-	SearchPosts(ctx context.Context, query string, limit, offset int) ([]domain.Post, error)
+	ListPostsFiltered(ctx context.Context, limit, offset int, sort, period, author, tag string, includeArchived bool) ([]domain.PostListItem, error)
+	SearchPosts(ctx context.Context, query string, limit, offset int, includeArchived bool) ([]domain.PostListItem, error)
+	ListAuthorPosts(ctx context.Context, authorID int64, status string, limit, offset int, includeArchived bool) ([]domain.Post, error)
+	GetAuthorStats(ctx context.Context, authorID int64, topTags int) (*domain.AuthorStats, error)
 	IncrementView(ctx context.Context, postID int64) error
 	IncrementLike(ctx context.Context, postID int64) error
 	DecrementLike(ctx context.Context, postID int64) error
 	IncrementComments(ctx context.Context, postID int64) error
 	DecrementComments(ctx context.Context, postID int64) error
+	AdjustComments(ctx context.Context, postID, delta int64) error
 	AddLike(ctx context.Context, postID, userID int64) error
 	RemoveLike(ctx context.Context, postID, userID int64) error
 	HasLiked(ctx context.Context, postID, userID int64) (bool, error)
+	LikedPostIDs(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error)
+	ListLikedPosts(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.LikedPost, error)
+	ListPostLikers(ctx context.Context, postID int64, before *util.Cursor, limit int) ([]domain.PostLike, error)
+	AddBookmark(ctx context.Context, postID, userID int64, folder string) error
+	RemoveBookmark(ctx context.Context, postID, userID int64) error
+	BookmarkedPostIDs(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error)
+	ListBookmarks(ctx context.Context, userID int64, folder *string, before *util.Cursor, limit int) ([]domain.BookmarkedPost, error)
 	UpdateAuthorInfo(ctx context.Context, authorID int64, username, avatarURL string) error
+	AnonymizeAuthorPosts(ctx context.Context, authorID int64) error
+	SoftDeleteAuthorPosts(ctx context.Context, authorID int64) error
+	SetAuthorPostsHidden(ctx context.Context, authorID int64, hidden bool) error
+	AddUserFollow(ctx context.Context, followerID, authorID int64) error
+	RemoveUserFollow(ctx context.Context, followerID, authorID int64) error
+	AddTagFollow(ctx context.Context, followerID int64, tag string) error
+	RemoveTagFollow(ctx context.Context, followerID int64, tag string) error
+	AddUpload(ctx context.Context, key string, uploaderID int64) error
+	OwnedUploadKeys(ctx context.Context, uploaderID int64, keys []string) (map[string]bool, error)
+	AttachmentKeysInUse(ctx context.Context, exceptPostID int64, keys []string) (map[string]bool, error)
+	ListFeed(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.PostListItem, error)
+	ListPostsAfterID(ctx context.Context, afterID int64, f PostFilter, limit int) ([]domain.Post, error)
+	ArchivePosts(ctx context.Context, createdBefore time.Time, limit int) (int64, error)
+}
+
+// PostUpdate lists the changes of UpdatePost; nil fields are left unchanged.
+type PostUpdate struct {
+	Title       *string
+	Body        *render.Content
+	Tags        []string
+	Status      *string
+	Attachments []domain.Attachment
+	Mentions    []string
+}
+
+// PostFilter narrows ListPostsAfterID; zero fields match everything.
+type PostFilter struct {
+	AuthorID int64
+	Since    time.Time
+	Until    time.Time
 }
 
 type postRepository struct {
-	db *pgxpool.Pool
+	cluster *Cluster
+	q       *db.Queries
+	hot     util.HotRanking
+	timeout time.Duration
+}
+
+// NewPostRepository writes to the primary of the cluster; list, search, get
+// and stats reads go to a replica unless the context asks for WithPrimary.
+// Every statement is cancelled after statementTimeout, 0 disables it.
+func NewPostRepository(cluster *Cluster, hot util.HotRanking, statementTimeout time.Duration) PostRepository {
+	return &postRepository{
+		cluster: cluster,
+		q:       db.New(withTimeout(cluster.Primary(), statementTimeout)),
+		hot:     hot,
+		timeout: statementTimeout,
+	}
 }
 
-func NewPostRepository(db *pgxpool.Pool) PostRepository {
-	return &postRepository{db: db}
+// read returns the queries for a read-only call.
+func (r *postRepository) read(ctx context.Context) *db.Queries {
+	return db.New(withTimeout(r.cluster.Reader(ctx), r.timeout))
 }
 
-const postSelectFields = `
-	id, title, content, author_id, author_username, author_avatar_url,
-	tags, views, likes_count, comments_count, created_at, updated_at
-`
+// inTx returns the queries running in tx.
+func (r *postRepository) inTx(tx pgx.Tx) *db.Queries {
+	return db.New(withTimeout(tx, r.timeout))
+}
 
-func scanPost(row pgx.Row) (*domain.Post, error) {
-	var p domain.Post
-	err := row.Scan(
-		&p.ID, &p.Title, &p.Content,
-		&p.AuthorID, &p.AuthorUsername, &p.AuthorAvatarURL,
-		&p.Tags, &p.Views, &p.LikesCount, &p.CommentsCount,
-		&p.CreatedAt, &p.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
+// TopPeriods maps the top period to the window of like timestamps it counts.
+var TopPeriods = map[string]string{
+	"day":   "1 day",
+	"week":  "7 days",
+	"month": "1 month",
+	"year":  "1 year",
+	"all":   "",
+}
+
+// postFromRow converts a full post row. Every query selecting the same columns
+// has a row type convertible to db.GetPostRow.
+func postFromRow(r db.GetPostRow) domain.Post {
+	return domain.Post{
+		ID:              r.ID,
+		Title:           r.Title,
+		Content:         r.Content,
+		ContentFormat:   r.ContentFormat,
+		ContentHTML:     r.ContentHTML,
+		Excerpt:         r.Excerpt,
+		AuthorID:        r.AuthorID,
+		AuthorUsername:  r.AuthorUsername,
+		AuthorAvatarURL: r.AuthorAvatarURL,
+		Tags:            r.Tags,
+		Status:          r.Status,
+		Views:           r.Views,
+		LikesCount:      r.LikesCount,
+		CommentsCount:   r.CommentsCount,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+		DeletedAt:       r.DeletedAt,
 	}
-	return &p, nil
 }
 
-func scanPosts(rows pgx.Rows) ([]domain.Post, error) {
-	posts := make([]domain.Post, 0)
-	for rows.Next() {
-		var p domain.Post
-		if err := rows.Scan(
-			&p.ID, &p.Title, &p.Content,
-			&p.AuthorID, &p.AuthorUsername, &p.AuthorAvatarURL,
-			&p.Tags, &p.Views, &p.LikesCount, &p.CommentsCount,
-			&p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
-			return nil, err
+func postsFromRows[T ~struct {
+	ID              int64
+	Title           string
+	Content         string
+	ContentFormat   string
+	ContentHTML     string
+	Excerpt         string
+	AuthorID        int64
+	AuthorUsername  string
+	AuthorAvatarURL string
+	Tags            []string
+	Status          string
+	Views           int64
+	LikesCount      int64
+	CommentsCount   int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       *time.Time
+}](rows []T) []domain.Post {
+	posts := make([]domain.Post, len(rows))
+	for i, r := range rows {
+		posts[i] = postFromRow(db.GetPostRow(r))
+	}
+	return posts
+}
+
+// listItemsFromRows converts list projection rows, see postFromRow.
+func listItemsFromRows[T ~struct {
+	ID              int64
+	Title           string
+	Excerpt         string
+	AuthorID        int64
+	AuthorUsername  string
+	AuthorAvatarURL string
+	Tags            []string
+	Views           int64
+	LikesCount      int64
+	CommentsCount   int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}](rows []T) []domain.PostListItem {
+	items := make([]domain.PostListItem, len(rows))
+	for i, row := range rows {
+		r := db.ListPostsNewRow(row)
+		items[i] = domain.PostListItem{
+			ID:              r.ID,
+			Title:           r.Title,
+			Excerpt:         r.Excerpt,
+			AuthorID:        r.AuthorID,
+			AuthorUsername:  r.AuthorUsername,
+			AuthorAvatarURL: r.AuthorAvatarURL,
+			Tags:            r.Tags,
+			Views:           r.Views,
+			LikesCount:      r.LikesCount,
+			CommentsCount:   r.CommentsCount,
+			CreatedAt:       r.CreatedAt,
+			UpdatedAt:       r.UpdatedAt,
 		}
-		posts = append(posts, p)
 	}
-	return posts, rows.Err()
+	return items
+}
+
+// cursorArgs splits an optional cursor into the before_time/before_id arguments.
+func cursorArgs(before *util.Cursor) (*time.Time, *int64) {
+	if before == nil {
+		return nil, nil
+	}
+	return &before.Time, &before.ID
+}
+
+// optional maps the zero value to NULL, for sqlc.narg filters.
+func optional[T comparable](v T) *T {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return &v
+}
+
+// ErrPostNotFound keeps the message the handlers map to 404.
+var ErrPostNotFound = errors.New("post not found")
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrPostNotFound
+	}
+	return err
 }
 
 func (r *postRepository) CreatePost(ctx context.Context, post *domain.Post) (int64, error) {
-	query := `
-		INSERT INTO posts (title, content, author_id, author_username, author_avatar_url, tags)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
-	`
-	var id int64
-	var createdAt, updatedAt time.Time
-	err := r.db.QueryRow(ctx, query,
-		post.Title, post.Content, post.AuthorID,
-		post.AuthorUsername, post.AuthorAvatarURL, post.Tags,
-	).Scan(&id, &createdAt, &updatedAt)
+	tx, err := r.cluster.Primary().Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	q := r.inTx(tx)
+
+	row, err := q.CreatePost(ctx, db.CreatePostParams{
+		Title:           post.Title,
+		Content:         post.Content,
+		ContentFormat:   post.ContentFormat,
+		ContentHTML:     post.ContentHTML,
+		ContentText:     post.ContentText,
+		Excerpt:         post.Excerpt,
+		AuthorID:        post.AuthorID,
+		AuthorUsername:  post.AuthorUsername,
+		AuthorAvatarURL: post.AuthorAvatarURL,
+		Tags:            post.Tags,
+		Status:          post.Status,
+	})
 	if err != nil {
 		return 0, err
 	}
-	post.ID = id
-	post.CreatedAt = createdAt
-	post.UpdatedAt = updatedAt
-	return id, nil
+	if err := insertAttachments(ctx, q, row.ID, post.Attachments); err != nil {
+		return 0, err
+	}
+	if err := insertMentions(ctx, q, row.ID, post.Mentions); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	post.ID = row.ID
+	post.CreatedAt = row.CreatedAt
+	post.UpdatedAt = row.UpdatedAt
+	return row.ID, nil
 }
 
 func (r *postRepository) GetPost(ctx context.Context, id int64) (*domain.Post, error) {
-	query := fmt.Sprintf(`
-		SELECT %s FROM posts
-		WHERE id = $1 AND deleted_at IS NULL
-	`, postSelectFields)
-	p, err := scanPost(r.db.QueryRow(ctx, query, id))
+	q := r.read(ctx)
+	archived := false
+	row, err := q.GetPost(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var archivedRow db.GetArchivedPostRow
+		archivedRow, err = q.GetArchivedPost(ctx, id)
+		row, archived = db.GetPostRow(archivedRow), true
+	}
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("post not found")
-		}
+		return nil, notFound(err)
+	}
+	p := postFromRow(row)
+	p.Archived = archived
+	if p.Attachments, err = listAttachments(ctx, q, id); err != nil {
 		return nil, err
 	}
-	return p, nil
+	if p.Mentions, err = q.ListMentions(ctx, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
 
-func (r *postRepository) UpdatePost(ctx context.Context, id int64, title *string, content *string, tags []string) (*domain.Post, error) {
-	setClauses := []string{"updated_at = NOW()"}
-	args := []any{id}
-	argIdx := 2
+func (r *postRepository) UpdatePost(ctx context.Context, id int64, upd PostUpdate) (*domain.Post, error) {
+	arg := db.UpdatePostParams{ID: id, Title: upd.Title, Tags: upd.Tags, Status: upd.Status}
+	if b := upd.Body; b != nil {
+		arg.Content, arg.ContentFormat, arg.ContentHTML = &b.Source, &b.Format, &b.HTML
+		arg.ContentText, arg.Excerpt = &b.Text, &b.Excerpt
+	}
+
+	tx, err := r.cluster.Primary().Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	q := r.inTx(tx)
 
-	if title != nil {
-		setClauses = append(setClauses, fmt.Sprintf("title = $%d", argIdx))
-		args = append(args, *title)
-		argIdx++
+	row, err := q.UpdatePost(ctx, arg)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	p := postFromRow(db.GetPostRow(row))
+	if upd.Attachments != nil {
+		if err := q.DeleteAttachments(ctx, id); err != nil {
+			return nil, err
+		}
+		if err := insertAttachments(ctx, q, id, upd.Attachments); err != nil {
+			return nil, err
+		}
+	}
+	if upd.Mentions != nil {
+		if err := q.DeleteMentionsExcept(ctx, db.DeleteMentionsExceptParams{PostID: id, Keep: upd.Mentions}); err != nil {
+			return nil, err
+		}
+		if err := insertMentions(ctx, q, id, upd.Mentions); err != nil {
+			return nil, err
+		}
+	}
+	if p.Attachments, err = listAttachments(ctx, q, id); err != nil {
+		return nil, err
 	}
-	if content != nil {
-		setClauses = append(setClauses, fmt.Sprintf("content = $%d", argIdx))
-		args = append(args, *content)
-		argIdx++
+	if p.Mentions, err = q.ListMentions(ctx, id); err != nil {
+		return nil, err
 	}
-	if tags != nil {
-		setClauses = append(setClauses, fmt.Sprintf("tags = $%d", argIdx))
-		args = append(args, tags)
-		argIdx++
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
+	return &p, nil
+}
 
-	query := fmt.Sprintf(`
-		UPDATE posts SET %s
-		WHERE id = $1 AND deleted_at IS NULL
-		RETURNING %s
-	`, strings.Join(setClauses, ", "), postSelectFields)
+func insertAttachments(ctx context.Context, q *db.Queries, postID int64, attachments []domain.Attachment) error {
+	for i := range attachments {
+		a := &attachments[i]
+		id, err := q.InsertAttachment(ctx, db.InsertAttachmentParams{
+			PostID:     postID,
+			Position:   int32(i),
+			URL:        a.URL,
+			StorageKey: a.Key,
+			MimeType:   a.MimeType,
+			SizeBytes:  a.SizeBytes,
+			Width:      int32(a.Width),
+			Height:     int32(a.Height),
+			AltText:    a.AltText,
+		})
+		if err != nil {
+			return err
+		}
+		a.ID = id
+	}
+	return nil
+}
 
-	p, err := scanPost(r.db.QueryRow(ctx, query, args...))
+func listAttachments(ctx context.Context, q *db.Queries, postID int64) ([]domain.Attachment, error) {
+	rows, err := q.ListAttachments(ctx, postID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("post not found")
-		}
 		return nil, err
 	}
-	return p, nil
+	attachments := make([]domain.Attachment, len(rows))
+	for i, a := range rows {
+		attachments[i] = domain.Attachment{
+			ID:        a.ID,
+			URL:       a.URL,
+			Key:       a.StorageKey,
+			MimeType:  a.MimeType,
+			SizeBytes: a.SizeBytes,
+			Width:     int(a.Width),
+			Height:    int(a.Height),
+			AltText:   a.AltText,
+		}
+	}
+	return attachments, nil
 }
 
 func (r *postRepository) DeletePost(ctx context.Context, id int64) error {
-	result, err := r.db.Exec(ctx,
-		`UPDATE posts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	n, err := r.q.DeletePost(ctx, id)
 	if err != nil {
 		return err
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("post not found")
+	if n == 0 {
+		return ErrPostNotFound
 	}
 	return nil
 }
 
-func (r *postRepository) ListPostsFiltered(ctx context.Context, limit, offset int, sort, author, tag string) ([]domain.Post, error) {
-	conditions := []string{"deleted_at IS NULL"}
-	args := []any{}
-	argIdx := 1
-
-	if author != "" {
-		conditions = append(conditions, fmt.Sprintf("author_username = $%d", argIdx))
-		args = append(args, author)
-		argIdx++
-	}
-	if tag != "" {
-		conditions = append(conditions, fmt.Sprintf("$%d = ANY(tags)", argIdx))
-		args = append(args, tag)
-		argIdx++
+func insertMentions(ctx context.Context, q *db.Queries, postID int64, usernames []string) error {
+	if len(usernames) == 0 {
+		return nil
 	}
+	return q.InsertMentions(ctx, db.InsertMentionsParams{PostID: postID, Usernames: usernames})
+}
+
+func (r *postRepository) ListPostsFiltered(ctx context.Context, limit, offset int, sort, period, author, tag string, includeArchived bool) ([]domain.PostListItem, error) {
+	q := r.read(ctx)
+	a, t := optional(author), optional(util.NormalizeTag(tag))
+	lim, off := int32(limit), int32(offset)
+	w := r.hot.Weights()
 
-	orderBy := "created_at DESC"
 	switch sort {
 	case "hot":
-		orderBy = "(likes_count * 0.8 + comments_count * 0.5 + ln(views + 1)) DESC"
+		switch r.hot.Name() {
+		case util.HotRankingReddit:
+			rows, err := q.ListPostsHotReddit(ctx, db.ListPostsHotRedditParams{
+				Author: a, Tag: t, IncludeArchived: includeArchived, LikesWeight: w.Likes, CommentsWeight: w.Comments,
+				Limit: lim, Offset: off,
+			})
+			return listItemsFromRows(rows), err
+		case util.HotRankingHN:
+			rows, err := q.ListPostsHotHN(ctx, db.ListPostsHotHNParams{
+				Author: a, Tag: t, IncludeArchived: includeArchived, LikesWeight: w.Likes, CommentsWeight: w.Comments,
+				ViewsWeight: w.Views, Gravity: w.Gravity, Limit: lim, Offset: off,
+			})
+			return listItemsFromRows(rows), err
+		default:
+			rows, err := q.ListPostsHotLinear(ctx, db.ListPostsHotLinearParams{
+				Author: a, Tag: t, IncludeArchived: includeArchived, LikesWeight: w.Likes, CommentsWeight: w.Comments,
+				ViewsWeight: w.Views, Limit: lim, Offset: off,
+			})
+			return listItemsFromRows(rows), err
+		}
 	case "top":
-		orderBy = "likes_count DESC"
+		rows, err := q.ListPostsTop(ctx, db.ListPostsTopParams{
+			Author: a, Tag: t, IncludeArchived: includeArchived, Period: optional(TopPeriods[period]), Limit: lim, Offset: off,
+		})
+		return listItemsFromRows(rows), err
 	}
+	rows, err := q.ListPostsNew(ctx, db.ListPostsNewParams{
+		Author: a, Tag: t, IncludeArchived: includeArchived, Limit: lim, Offset: off,
+	})
+	return listItemsFromRows(rows), err
+}
 
-	args = append(args, limit, offset)
-	query := fmt.Sprintf(`
-		SELECT %s FROM posts
-		WHERE %s
-		ORDER BY %s
-		LIMIT $%d OFFSET $%d
-	`, postSelectFields,
-		strings.Join(conditions, " AND "),
-		orderBy, argIdx, argIdx+1)
+func (r *postRepository) SearchPosts(ctx context.Context, query string, limit, offset int, includeArchived bool) ([]domain.PostListItem, error) {
+	rows, err := r.read(ctx).SearchPosts(ctx, db.SearchPostsParams{
+		Query: query, IncludeArchived: includeArchived, Limit: int32(limit), Offset: int32(offset),
+	})
+	return listItemsFromRows(rows), err
+}
+
+func (r *postRepository) ListAuthorPosts(ctx context.Context, authorID int64, status string, limit, offset int, includeArchived bool) ([]domain.Post, error) {
+	rows, err := r.read(ctx).ListAuthorPosts(ctx, db.ListAuthorPostsParams{
+		AuthorID: authorID, Status: status, IncludeArchived: includeArchived, Limit: int32(limit), Offset: int32(offset),
+	})
+	return postsFromRows(rows), err
+}
 
-	rows, err := r.db.Query(ctx, query, args...)
+func (r *postRepository) GetAuthorStats(ctx context.Context, authorID int64, topTags int) (*domain.AuthorStats, error) {
+	q := r.read(ctx)
+	totals, err := q.GetAuthorTotals(ctx, authorID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	return scanPosts(rows)
-}
-
-func (r *postRepository) SearchPosts(ctx context.Context, query string, limit, offset int) ([]domain.Post, error) {
-	q := fmt.Sprintf(`
-		SELECT %s FROM posts
-		WHERE deleted_at IS NULL
-		  AND search_vector @@ plainto_tsquery('russian', $1)
-		ORDER BY ts_rank(search_vector, plainto_tsquery('russian', $1)) DESC
-		LIMIT $2 OFFSET $3
-	`, postSelectFields)
-
-	rows, err := r.db.Query(ctx, q, query, limit, offset)
+	tags, err := q.ListAuthorTopTags(ctx, db.ListAuthorTopTagsParams{AuthorID: authorID, Limit: int32(topTags)})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	return scanPosts(rows)
+	stats := domain.AuthorStats{
+		AuthorID:      authorID,
+		TotalPosts:    totals.TotalPosts,
+		LikesReceived: totals.LikesReceived,
+		Views:         totals.Views,
+		TopTags:       make([]domain.TagCount, len(tags)),
+	}
+	for i, t := range tags {
+		stats.TopTags[i] = domain.TagCount{Tag: t.Tag, Count: t.Cnt}
+	}
+	return &stats, nil
 }
 
 func (r *postRepository) IncrementView(ctx context.Context, postID int64) error {
-	_, err := r.db.Exec(ctx, `UPDATE posts SET views = views + 1 WHERE id = $1`, postID)
-	return err
+	return r.q.IncrementView(ctx, postID)
 }
 
 func (r *postRepository) IncrementLike(ctx context.Context, postID int64) error {
-	_, err := r.db.Exec(ctx, `UPDATE posts SET likes_count = likes_count + 1 WHERE id = $1`, postID)
-	return err
+	return r.q.IncrementLike(ctx, postID)
 }
 
 func (r *postRepository) DecrementLike(ctx context.Context, postID int64) error {
-	_, err := r.db.Exec(ctx, `UPDATE posts SET likes_count = GREATEST(likes_count - 1, 0) WHERE id = $1`, postID)
-	return err
+	return r.q.DecrementLike(ctx, postID)
 }
 
 func (r *postRepository) IncrementComments(ctx context.Context, postID int64) error {
-	_, err := r.db.Exec(ctx, `UPDATE posts SET comments_count = comments_count + 1 WHERE id = $1`, postID)
-	return err
+	return r.q.IncrementComments(ctx, postID)
 }
 
 func (r *postRepository) DecrementComments(ctx context.Context, postID int64) error {
-	_, err := r.db.Exec(ctx, `UPDATE posts SET comments_count = GREATEST(comments_count - 1, 0) WHERE id = $1`, postID)
-	return err
+	return r.q.DecrementComments(ctx, postID)
+}
+
+func (r *postRepository) AdjustComments(ctx context.Context, postID, delta int64) error {
+	return r.q.AdjustComments(ctx, db.AdjustCommentsParams{ID: postID, Delta: delta})
 }
 
 func (r *postRepository) AddLike(ctx context.Context, postID, userID int64) error {
-	_, err := r.db.Exec(ctx,
-		`INSERT INTO post_likes (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
-		postID, userID)
-	return err
+	return r.q.AddLike(ctx, db.AddLikeParams{PostID: postID, UserID: userID})
 }
 
 func (r *postRepository) RemoveLike(ctx context.Context, postID, userID int64) error {
-	_, err := r.db.Exec(ctx,
-		`DELETE FROM post_likes WHERE post_id = $1 AND user_id = $2`,
-		postID, userID)
-	return err
+	return r.q.RemoveLike(ctx, db.RemoveLikeParams{PostID: postID, UserID: userID})
 }
 
 func (r *postRepository) HasLiked(ctx context.Context, postID, userID int64) (bool, error) {
-	var exists bool
-	err := r.db.QueryRow(ctx,
-		`SELECT EXISTS(SELECT 1 FROM post_likes WHERE post_id = $1 AND user_id = $2)`,
-		postID, userID,
-	).Scan(&exists)
-	return exists, err
+	return r.read(ctx).HasLiked(ctx, db.HasLikedParams{PostID: postID, UserID: userID})
+}
+
+func (r *postRepository) LikedPostIDs(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error) {
+	liked := make(map[int64]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return liked, nil
+	}
+	ids, err := r.read(ctx).LikedPostIDs(ctx, db.LikedPostIDsParams{UserID: userID, PostIds: postIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
+
+func (r *postRepository) ListLikedPosts(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.LikedPost, error) {
+	bt, bid := cursorArgs(before)
+	rows, err := r.read(ctx).ListLikedPosts(ctx, db.ListLikedPostsParams{
+		UserID: userID, BeforeTime: bt, BeforeID: bid, Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]domain.LikedPost, len(rows))
+	for i, row := range rows {
+		posts[i] = domain.LikedPost{
+			Post: postFromRow(db.GetPostRow{
+				ID: row.ID, Title: row.Title, Content: row.Content, ContentFormat: row.ContentFormat,
+				ContentHTML: row.ContentHTML, Excerpt: row.Excerpt, AuthorID: row.AuthorID,
+				AuthorUsername: row.AuthorUsername, AuthorAvatarURL: row.AuthorAvatarURL, Tags: row.Tags,
+				Status: row.Status, Views: row.Views, LikesCount: row.LikesCount, CommentsCount: row.CommentsCount,
+				CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt, DeletedAt: row.DeletedAt,
+			}),
+			LikedAt: row.LikedAt,
+		}
+	}
+	return posts, nil
+}
+
+func (r *postRepository) ListPostLikers(ctx context.Context, postID int64, before *util.Cursor, limit int) ([]domain.PostLike, error) {
+	bt, bid := cursorArgs(before)
+	rows, err := r.read(ctx).ListPostLikers(ctx, db.ListPostLikersParams{
+		PostID: postID, BeforeTime: bt, BeforeID: bid, Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	likes := make([]domain.PostLike, len(rows))
+	for i, l := range rows {
+		likes[i] = domain.PostLike{UserID: l.UserID, LikedAt: l.CreatedAt}
+	}
+	return likes, nil
+}
+
+func (r *postRepository) AddBookmark(ctx context.Context, postID, userID int64, folder string) error {
+	return r.q.AddBookmark(ctx, db.AddBookmarkParams{PostID: postID, UserID: userID, Folder: folder})
+}
+
+func (r *postRepository) RemoveBookmark(ctx context.Context, postID, userID int64) error {
+	n, err := r.q.RemoveBookmark(ctx, db.RemoveBookmarkParams{PostID: postID, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("not bookmarked")
+	}
+	return nil
+}
+
+func (r *postRepository) BookmarkedPostIDs(ctx context.Context, userID int64, postIDs []int64) (map[int64]bool, error) {
+	bookmarked := make(map[int64]bool, len(postIDs))
+	if len(postIDs) == 0 {
+		return bookmarked, nil
+	}
+	ids, err := r.read(ctx).BookmarkedPostIDs(ctx, db.BookmarkedPostIDsParams{UserID: userID, PostIds: postIDs})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
+
+func (r *postRepository) ListBookmarks(ctx context.Context, userID int64, folder *string, before *util.Cursor, limit int) ([]domain.BookmarkedPost, error) {
+	bt, bid := cursorArgs(before)
+	rows, err := r.read(ctx).ListBookmarks(ctx, db.ListBookmarksParams{
+		UserID: userID, Folder: folder, BeforeTime: bt, BeforeID: bid, Limit: int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	posts := make([]domain.BookmarkedPost, len(rows))
+	for i, row := range rows {
+		posts[i] = domain.BookmarkedPost{
+			Post: postFromRow(db.GetPostRow{
+				ID: row.ID, Title: row.Title, Content: row.Content, ContentFormat: row.ContentFormat,
+				ContentHTML: row.ContentHTML, Excerpt: row.Excerpt, AuthorID: row.AuthorID,
+				AuthorUsername: row.AuthorUsername, AuthorAvatarURL: row.AuthorAvatarURL, Tags: row.Tags,
+				Status: row.Status, Views: row.Views, LikesCount: row.LikesCount, CommentsCount: row.CommentsCount,
+				CreatedAt: row.CreatedAt, UpdatedAt: row.UpdatedAt, DeletedAt: row.DeletedAt,
+			}),
+			Folder:       row.Folder,
+			BookmarkedAt: row.BookmarkedAt,
+		}
+	}
+	return posts, nil
 }
 
 func (r *postRepository) UpdateAuthorInfo(ctx context.Context, authorID int64, username, avatarURL string) error {
-	_, err := r.db.Exec(ctx,
-		`UPDATE posts SET author_username = $2, author_avatar_url = $3 WHERE author_id = $1`,
-		authorID, username, avatarURL)
-	return err
+	return r.q.UpdateAuthorInfo(ctx, db.UpdateAuthorInfoParams{
+		AuthorID: authorID, AuthorUsername: username, AuthorAvatarURL: avatarURL,
+	})
+}
+
+func (r *postRepository) AnonymizeAuthorPosts(ctx context.Context, authorID int64) error {
+	return r.q.AnonymizeAuthorPosts(ctx, db.AnonymizeAuthorPostsParams{
+		AuthorID: authorID, AuthorUsername: domain.DeletedAuthorUsername,
+	})
+}
+
+func (r *postRepository) SoftDeleteAuthorPosts(ctx context.Context, authorID int64) error {
+	return r.q.SoftDeleteAuthorPosts(ctx, authorID)
+}
+
+// SetAuthorPostsHidden moves published posts of the author to hidden and back;
+// drafts are left alone.
+func (r *postRepository) SetAuthorPostsHidden(ctx context.Context, authorID int64, hidden bool) error {
+	from, to := domain.PostStatusPublished, domain.PostStatusHidden
+	if !hidden {
+		from, to = to, from
+	}
+	return r.q.SetAuthorPostsStatus(ctx, db.SetAuthorPostsStatusParams{AuthorID: authorID, FromStatus: from, ToStatus: to})
+}
+
+func (r *postRepository) AddUserFollow(ctx context.Context, followerID, authorID int64) error {
+	return r.q.AddUserFollow(ctx, db.AddUserFollowParams{FollowerID: followerID, AuthorID: authorID})
+}
+
+func (r *postRepository) RemoveUserFollow(ctx context.Context, followerID, authorID int64) error {
+	return r.q.RemoveUserFollow(ctx, db.RemoveUserFollowParams{FollowerID: followerID, AuthorID: authorID})
+}
+
+func (r *postRepository) AddTagFollow(ctx context.Context, followerID int64, tag string) error {
+	return r.q.AddTagFollow(ctx, db.AddTagFollowParams{FollowerID: followerID, Tag: tag})
+}
+
+func (r *postRepository) RemoveTagFollow(ctx context.Context, followerID int64, tag string) error {
+	return r.q.RemoveTagFollow(ctx, db.RemoveTagFollowParams{FollowerID: followerID, Tag: tag})
+}
+
+func (r *postRepository) AddUpload(ctx context.Context, key string, uploaderID int64) error {
+	return r.q.AddUpload(ctx, db.AddUploadParams{StorageKey: key, UploaderID: uploaderID})
+}
+
+// OwnedUploadKeys reports which of keys were uploaded by uploaderID. It reads
+// the primary: a key is checked right after its upload.
+func (r *postRepository) OwnedUploadKeys(ctx context.Context, uploaderID int64, keys []string) (map[string]bool, error) {
+	owned := make(map[string]bool, len(keys))
+	if len(keys) == 0 {
+		return owned, nil
+	}
+	rows, err := r.q.OwnedUploadKeys(ctx, db.OwnedUploadKeysParams{UploaderID: uploaderID, Keys: keys})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range rows {
+		owned[key] = true
+	}
+	return owned, nil
+}
+
+// AttachmentKeysInUse reports which of keys are attached to a live post other
+// than exceptPostID, archived posts included.
+func (r *postRepository) AttachmentKeysInUse(ctx context.Context, exceptPostID int64, keys []string) (map[string]bool, error) {
+	inUse := make(map[string]bool, len(keys))
+	if len(keys) == 0 {
+		return inUse, nil
+	}
+	rows, err := r.q.AttachmentKeysInUse(ctx, db.AttachmentKeysInUseParams{Keys: keys, ExceptPostID: exceptPostID})
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range rows {
+		inUse[key] = true
+	}
+	return inUse, nil
+}
+
+func (r *postRepository) ListFeed(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.PostListItem, error) {
+	bt, bid := cursorArgs(before)
+	rows, err := r.read(ctx).ListFeed(ctx, db.ListFeedParams{UserID: userID, BeforeTime: bt, BeforeID: bid, Limit: int32(limit)})
+	return listItemsFromRows(rows), err
+}
+
+// ListPostsAfterID walks published posts in id order, with their mentions.
+func (r *postRepository) ListPostsAfterID(ctx context.Context, afterID int64, f PostFilter, limit int) ([]domain.Post, error) {
+	arg := db.ListPostsAfterIDParams{AfterID: afterID, AuthorID: optional(f.AuthorID), Limit: int32(limit)}
+	if !f.Since.IsZero() {
+		arg.Since = &f.Since
+	}
+	if !f.Until.IsZero() {
+		arg.Until = &f.Until
+	}
+	q := r.read(ctx)
+	rows, err := q.ListPostsAfterID(ctx, arg)
+	if err != nil || len(rows) == 0 {
+		return postsFromRows(rows), err
+	}
+	posts := postsFromRows(rows)
+
+	ids := make([]int64, len(posts))
+	byID := make(map[int64]*domain.Post, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+		byID[posts[i].ID] = &posts[i]
+		posts[i].Mentions = []string{}
+	}
+	mentions, err := q.ListMentionsOfPosts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		byID[m.PostID].Mentions = append(byID[m.PostID].Mentions, m.Username)
+	}
+	return posts, nil
+}
+
+// ArchivePosts moves up to limit soft-deleted posts and posts created before
+// createdBefore (drafts stay) into posts_archive and returns how many moved.
+func (r *postRepository) ArchivePosts(ctx context.Context, createdBefore time.Time, limit int) (int64, error) {
+	return r.q.ArchivePosts(ctx, db.ArchivePostsParams{CreatedBefore: createdBefore, Limit: int32(limit)})
 }
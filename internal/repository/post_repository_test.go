@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"post-service/internal/domain"
+	"post-service/internal/render"
+	"post-service/internal/util"
+	"post-service/migration"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRepo migrates the database at TEST_DATABASE_URL and returns a
+// repository on it, together with an author id no other run has used.
+func newTestRepo(t *testing.T) (PostRepository, int64) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	m, err := migration.New(url)
+	if err != nil {
+		t.Fatalf("migration.New: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	m.Close()
+
+	pool, err := NewPool(context.Background(), url, PoolOptions{MaxConns: 4})
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	hot, err := util.NewHotRanking(util.HotRankingLinear, util.HotWeights{Likes: 0.8, Comments: 0.5, Views: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPostRepository(NewCluster(pool), hot, 5*time.Second), time.Now().UnixNano()
+}
+
+func createTestPost(t *testing.T, repo PostRepository, authorID int64, title string, tags ...string) *domain.Post {
+	t.Helper()
+	p := &domain.Post{
+		Title:          title,
+		Content:        "body of " + title,
+		ContentFormat:  render.FormatPlain,
+		ContentHTML:    "<p>body of " + title + "</p>",
+		ContentText:    "body of " + title,
+		Excerpt:        "body of " + title,
+		AuthorID:       authorID,
+		AuthorUsername: "author" + title,
+		Tags:           tags,
+		Status:         domain.PostStatusPublished,
+		Attachments:    []domain.Attachment{{URL: "http://x/a.png", Key: "a.png", MimeType: "image/png", SizeBytes: 10}},
+		Mentions:       []string{"bob"},
+	}
+	if _, err := repo.CreatePost(context.Background(), p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	return p
+}
+
+func TestPostRepositoryCRUD(t *testing.T) {
+	repo, authorID := newTestRepo(t)
+	ctx := context.Background()
+
+	created := createTestPost(t, repo, authorID, "first", "go")
+	got, err := repo.GetPost(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if got.Title != "first" || !slices.Equal(got.Tags, []string{"go"}) {
+		t.Errorf("GetPost = %+v", got)
+	}
+	if len(got.Attachments) != 1 || got.Attachments[0].ID == 0 || got.Attachments[0].Key != "a.png" {
+		t.Errorf("attachments = %+v", got.Attachments)
+	}
+	if !slices.Equal(got.Mentions, []string{"bob"}) {
+		t.Errorf("mentions = %v", got.Mentions)
+	}
+
+	title := "renamed"
+	updated, err := repo.UpdatePost(ctx, created.ID, PostUpdate{Title: &title, Mentions: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if updated.Title != title || updated.Content != created.Content || !slices.Equal(updated.Tags, []string{"go"}) {
+		t.Errorf("UpdatePost changed more than the title: %+v", updated)
+	}
+	if !slices.Equal(updated.Mentions, []string{"alice"}) || len(updated.Attachments) != 1 {
+		t.Errorf("UpdatePost children = %v, %+v", updated.Mentions, updated.Attachments)
+	}
+
+	if err := repo.AddBookmark(ctx, created.ID, authorID, ""); err != nil {
+		t.Fatalf("AddBookmark: %v", err)
+	}
+	if err := repo.RemoveBookmark(ctx, created.ID, authorID); err != nil {
+		t.Fatalf("RemoveBookmark: %v", err)
+	}
+	if err := repo.RemoveBookmark(ctx, created.ID, authorID); err == nil || err.Error() != "not bookmarked" {
+		t.Errorf("second RemoveBookmark: %v", err)
+	}
+
+	key := fmt.Sprintf("uploads/%d.png", authorID)
+	if err := repo.AddUpload(ctx, key, authorID); err != nil {
+		t.Fatalf("AddUpload: %v", err)
+	}
+	if err := repo.AddUpload(ctx, key, authorID); err != nil {
+		t.Fatalf("second AddUpload: %v", err)
+	}
+	owned, err := repo.OwnedUploadKeys(ctx, authorID, []string{key, "a.png"})
+	if err != nil || !owned[key] || owned["a.png"] {
+		t.Errorf("OwnedUploadKeys = %v, %v", owned, err)
+	}
+	if owned, _ := repo.OwnedUploadKeys(ctx, authorID+1, []string{key}); owned[key] {
+		t.Errorf("key owned by another uploader")
+	}
+
+	if err := repo.DeletePost(ctx, created.ID); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if _, err := repo.GetPost(ctx, created.ID); err == nil || err.Error() != "post not found" {
+		t.Errorf("GetPost after delete: %v", err)
+	}
+	if err := repo.DeletePost(ctx, created.ID); err == nil || err.Error() != "post not found" {
+		t.Errorf("second DeletePost: %v", err)
+	}
+	if _, err := repo.UpdatePost(ctx, created.ID, PostUpdate{Title: &title}); err == nil || err.Error() != "post not found" {
+		t.Errorf("UpdatePost after delete: %v", err)
+	}
+}
+
+func TestPostRepositoryListFilters(t *testing.T) {
+	repo, authorID := newTestRepo(t)
+	ctx := context.Background()
+
+	tag := "tag" + time.Now().Format("150405.000000000")
+	a := createTestPost(t, repo, authorID, "a", tag)
+	b := createTestPost(t, repo, authorID, "b", tag)
+	createTestPost(t, repo, authorID, "c")
+	if err := repo.AddLike(ctx, a.ID, authorID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.IncrementLike(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sort := range []string{"new", "top", "hot"} {
+		items, err := repo.ListPostsFiltered(ctx, 10, 0, sort, "week", "", tag, false)
+		if err != nil {
+			t.Fatalf("ListPostsFiltered(%s): %v", sort, err)
+		}
+		var ids []int64
+		for _, it := range items {
+			ids = append(ids, it.ID)
+		}
+		want := []int64{a.ID, b.ID}
+		if sort == "new" {
+			want = []int64{b.ID, a.ID}
+		}
+		if !slices.Equal(ids, want) {
+			t.Errorf("ListPostsFiltered(%s) = %v, want %v", sort, ids, want)
+		}
+	}
+
+	// The tag of the query is normalized like the tags of posts
+	items, err := repo.ListPostsFiltered(ctx, 10, 0, "new", "week", "", " #"+strings.ToUpper(tag), false)
+	if err != nil || len(items) != 2 {
+		t.Errorf("ListPostsFiltered(#%s) = %+v, %v", strings.ToUpper(tag), items, err)
+	}
+
+	items, err = repo.ListPostsFiltered(ctx, 10, 0, "new", "", "author"+"b", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) == 0 || items[0].ID != b.ID {
+		t.Errorf("author filter = %+v", items)
+	}
+
+	posts, err := repo.ListPostsAfterID(ctx, a.ID, PostFilter{AuthorID: authorID}, 10)
+	if err != nil {
+		t.Fatalf("ListPostsAfterID: %v", err)
+	}
+	if len(posts) != 2 || posts[0].ID != b.ID || !slices.Equal(posts[0].Mentions, []string{"bob"}) {
+		t.Errorf("ListPostsAfterID = %+v", posts)
+	}
+}
+
+func TestPostRepositoryAuthorEvents(t *testing.T) {
+	repo, authorID := newTestRepo(t)
+	ctx := context.Background()
+
+	p := createTestPost(t, repo, authorID, "banned")
+	if err := repo.SetAuthorPostsHidden(ctx, authorID, true); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.GetPost(ctx, p.ID)
+	if err != nil || got.Status != domain.PostStatusHidden {
+		t.Fatalf("after ban: %+v, %v", got, err)
+	}
+	if err := repo.SetAuthorPostsHidden(ctx, authorID, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = repo.GetPost(ctx, p.ID); got.Status != domain.PostStatusPublished {
+		t.Errorf("after unban: status %q", got.Status)
+	}
+
+	if err := repo.AdjustComments(ctx, p.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AdjustComments(ctx, p.ID, -7); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = repo.GetPost(ctx, p.ID); got.CommentsCount != 0 {
+		t.Errorf("comments = %d, want 0", got.CommentsCount)
+	}
+
+	if err := repo.AnonymizeAuthorPosts(ctx, authorID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = repo.GetPost(ctx, p.ID); got.AuthorUsername != domain.DeletedAuthorUsername {
+		t.Errorf("after anonymize: author %q", got.AuthorUsername)
+	}
+
+	stats, err := repo.GetAuthorStats(ctx, authorID, 3)
+	if err != nil || stats.TotalPosts != 1 {
+		t.Errorf("GetAuthorStats = %+v, %v", stats, err)
+	}
+}
+
+func TestPostRepositoryArchive(t *testing.T) {
+	repo, authorID := newTestRepo(t)
+	ctx := context.Background()
+
+	tag := "archived" + time.Now().Format("150405.000000000")
+	p := createTestPost(t, repo, authorID, "old", tag)
+	for {
+		n, err := repo.ArchivePosts(ctx, time.Now().Add(time.Second), 100)
+		if err != nil {
+			t.Fatalf("ArchivePosts: %v", err)
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	got, err := repo.GetPost(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("GetPost of an archived post: %v", err)
+	}
+	if !got.Archived || got.Title != "old" || len(got.Attachments) != 1 || !slices.Equal(got.Mentions, []string{"bob"}) {
+		t.Errorf("archived post = %+v", got)
+	}
+
+	if err := repo.IncrementLike(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = repo.GetPost(ctx, p.ID); got.LikesCount != 1 {
+		t.Errorf("likes of the archived post = %d, want 1", got.LikesCount)
+	}
+
+	active, err := repo.ListPostsFiltered(ctx, 10, 0, "new", "all", "", tag, false)
+	if err != nil || len(active) != 0 {
+		t.Errorf("active list = %+v, %v", active, err)
+	}
+	all, err := repo.ListPostsFiltered(ctx, 10, 0, "new", "all", "", tag, true)
+	if err != nil || len(all) != 1 || all[0].ID != p.ID {
+		t.Errorf("list with archived = %+v, %v", all, err)
+	}
+
+	if err := repo.DeletePost(ctx, p.ID); err == nil || err.Error() != "post not found" {
+		t.Errorf("DeletePost of an archived post: %v", err)
+	}
+}
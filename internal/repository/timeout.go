@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"post-service/internal/repository/db"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// timeoutDB bounds every statement with a deadline on its context. Results are
+// read after Query and QueryRow return, so the deadline lasts until the rows
+// are closed or the row is scanned.
+type timeoutDB struct {
+	db      db.DBTX
+	timeout time.Duration
+}
+
+// withTimeout wraps d unless the timeout is off.
+func withTimeout(d db.DBTX, timeout time.Duration) db.DBTX {
+	if timeout <= 0 {
+		return d
+	}
+	return timeoutDB{db: d, timeout: timeout}
+}
+
+func (t timeoutDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.db.Exec(ctx, sql, args...)
+}
+
+func (t timeoutDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (t timeoutDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	return timeoutRow{row: t.db.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+type timeoutRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r timeoutRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+type timeoutRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r timeoutRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
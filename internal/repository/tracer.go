@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("post-service/internal/repository")
+
+// queryTracer records the duration of every query under the repository
+// method that runs it, taken from the "-- name: X" header sqlc puts in the SQL,
+// and wraps the query in a client span of that name.
+type queryTracer struct {
+	slow time.Duration
+}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	method string
+	at     time.Time
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	method := queryMethod(data.SQL)
+	ctx, _ = tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(method),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return context.WithValue(ctx, queryStartKey{}, queryStart{method: method, at: time.Now()})
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(start.at)
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+	queryDuration.WithLabelValues(start.method).Observe(elapsed.Seconds())
+	if data.Err != nil {
+		queryErrors.WithLabelValues(start.method).Inc()
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	if t.slow > 0 && elapsed >= t.slow {
+		slog.WarnContext(ctx, "slow query", "method", start.method, "duration_ms", elapsed.Milliseconds())
+	}
+}
+
+// queryMethod is the sqlc query name, or "other" for statements outside
+// sqlc/query.sql such as BEGIN and COMMIT.
+func queryMethod(sql string) string {
+	rest, ok := strings.CutPrefix(sql, "-- name: ")
+	if !ok {
+		return "other"
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
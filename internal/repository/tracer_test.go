@@ -0,0 +1,19 @@
+package repository
+
+import "testing"
+
+func TestQueryMethod(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"-- name: GetPost :one\nSELECT 1", "GetPost"},
+		{"-- name: DeletePost :execrows\nUPDATE posts", "DeletePost"},
+		{"begin", "other"},
+	}
+	for _, tt := range tests {
+		if got := queryMethod(tt.sql); got != tt.want {
+			t.Errorf("queryMethod(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -3,12 +3,16 @@ package router
 import (
 	"post-service/internal/handler"
 	"post-service/internal/middleware"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func Setup(app *fiber.App, h *handler.PostHandler) {
+// Setup registers the routes; uploads take bodies up to uploadBodyLimit, the
+// other routes the default limit.
+func Setup(app *fiber.App, h *handler.PostHandler, uh *handler.UploadHandler, uploadBodyLimit int) {
 	v1 := app.Group("/api/v1")
+	v1.Use(middleware.BodyLimit(fiber.DefaultBodyLimit, isUpload))
 
 	// Public
 	v1.Get("/posts/search", h.Search)
@@ -16,6 +20,12 @@ func Setup(app *fiber.App, h *handler.PostHandler) {
 	v1.Get("/posts/top", h.TopPosts)
 	v1.Get("/posts", h.ListPosts)
 	v1.Get("/posts/:id", h.GetPost)
+	v1.Get("/posts/:id/likes", h.PostLikers)
+	v1.Get("/users/:authorId/posts", h.AuthorPosts)
+	v1.Get("/users/:authorId/post-stats", h.AuthorStats)
+	v1.Get("/uploads/*", uh.Download) // access is checked by the URL signature
+	v1.Get("/events/schemas", handler.EventSchemas)
+	v1.Get("/events/schemas/:name", handler.EventSchema)
 
 	// Protected
 	auth := v1.Group("/", middleware.AuthRequired())
@@ -24,4 +34,15 @@ func Setup(app *fiber.App, h *handler.PostHandler) {
 	auth.Delete("/posts/:id", h.Delete)
 	auth.Post("/posts/:id/like", h.Like)
 	auth.Delete("/posts/:id/like", h.Unlike)
+	auth.Get("/me/posts", h.MyPosts)
+	auth.Put("/posts/:id/bookmark", h.Bookmark)
+	auth.Delete("/posts/:id/bookmark", h.Unbookmark)
+	auth.Get("/me/likes", h.MyLikes)
+	auth.Get("/me/bookmarks", h.MyBookmarks)
+	auth.Get("/feed", h.Feed)
+	auth.Post("/uploads", middleware.BodyLimit(uploadBodyLimit, nil), uh.Upload)
+}
+
+func isUpload(c *fiber.Ctx) bool {
+	return c.Method() == fiber.MethodPost && strings.TrimRight(c.Path(), "/") == "/api/v1/uploads"
 }
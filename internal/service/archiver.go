@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"post-service/internal/repository"
+	"time"
+)
+
+// Archiver moves posts older than a given age, and soft-deleted ones, to
+// posts_archive on every tick. Batches lock their rows with SKIP LOCKED, so
+// every instance can run one.
+type Archiver struct {
+	repo     repository.PostRepository
+	after    time.Duration
+	interval time.Duration
+	batch    int
+}
+
+func NewArchiver(repo repository.PostRepository, after, interval time.Duration, batch int) *Archiver {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Archiver{repo: repo, after: after, interval: interval, batch: max(batch, 1)}
+}
+
+// Run archives until ctx is done.
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if n, err := a.ArchiveOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "archive posts", "archived", n, "err", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "archived posts", "archived", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ArchiveOnce moves batches until one comes back short.
+func (a *Archiver) ArchiveOnce(ctx context.Context) (int64, error) {
+	var total int64
+	cutoff := time.Now().Add(-a.after)
+	for ctx.Err() == nil {
+		n, err := a.repo.ArchivePosts(ctx, cutoff, a.batch)
+		total += n
+		if err != nil || n < int64(a.batch) {
+			return total, err
+		}
+	}
+	return total, ctx.Err()
+}
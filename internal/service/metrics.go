@@ -0,0 +1,43 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	postsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "post_service_posts_created_total",
+		Help: "Posts created, drafts included.",
+	})
+	postsUpdated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "post_service_posts_updated_total",
+		Help: "Posts updated by their author.",
+	})
+	postsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "post_service_posts_deleted_total",
+		Help: "Posts deleted by their author.",
+	})
+	likes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "post_service_likes_total",
+		Help: "Likes added.",
+	})
+	unlikes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "post_service_unlikes_total",
+		Help: "Likes removed.",
+	})
+	views = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_views_total",
+		Help: "Post views: counted, or deduplicated as a repeat of the same user within a day.",
+	}, []string{"result"})
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "post_service_cache_requests_total",
+		Help: "Redis cache lookups by cache and result (hit or miss); the hit ratio is hit / (hit + miss).",
+	}, []string{"cache", "result"})
+	// The hot feed is ranked by the query of every request, there is no stored feed to rebuild
+	hotFeedDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "post_service_hot_feed_build_duration_seconds",
+		Help:    "Time to rank and load a page of the hot feed, viewer flags included.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	})
+)
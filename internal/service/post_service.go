@@ -2,13 +2,21 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"post-service/internal/domain"
 	"post-service/internal/dto"
 	"post-service/internal/event"
+	"post-service/internal/render"
 	"post-service/internal/repository"
+	"post-service/internal/storage"
+	"post-service/internal/util"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -17,77 +25,187 @@ type PostService interface {
 	GetPost(ctx context.Context, id int64, userID int64) (*domain.Post, error)
 	UpdatePost(ctx context.Context, id, userID int64, req *dto.UpdatePostRequest) (*domain.Post, error)
 	DeletePost(ctx context.Context, id, userID int64) error
-	ListPosts(ctx context.Context, q dto.ListPostsQuery, userID int64) ([]domain.Post, error)
-	SearchPosts(ctx context.Context, query string, limit, offset int) ([]domain.Post, error)
+	ListPosts(ctx context.Context, q dto.ListPostsQuery, userID int64) ([]domain.PostListItem, error)
+	SearchPosts(ctx context.Context, query string, limit, offset int, includeArchived bool, userID int64) ([]domain.PostListItem, error)
+	ListAuthorPosts(ctx context.Context, authorID, viewerID int64, q dto.ListAuthorPostsQuery) ([]domain.Post, error)
+	GetAuthorStats(ctx context.Context, authorID int64) (*domain.AuthorStats, error)
+	InvalidateAuthorStats(ctx context.Context, authorID int64)
 	IncrementView(ctx context.Context, postID, userID int64) error
 	Like(ctx context.Context, postID, userID int64) error
 	Unlike(ctx context.Context, postID, userID int64) error
+	ListLikedPosts(ctx context.Context, userID int64, cursor string, limit int) (*dto.Page[domain.LikedPost], error)
+	ListPostLikers(ctx context.Context, postID, viewerID int64, cursor string, limit int) (*dto.PostLikersResponse, error)
+	Bookmark(ctx context.Context, postID, userID int64, folder string) error
+	Unbookmark(ctx context.Context, postID, userID int64) error
+	ListBookmarks(ctx context.Context, userID int64, folder *string, cursor string, limit int) (*dto.Page[domain.BookmarkedPost], error)
+	Feed(ctx context.Context, userID int64, cursor string, limit int) (*dto.Page[domain.PostListItem], error)
 }
 
+const (
+	authorStatsTTL     = 5 * time.Minute
+	authorStatsTopTags = 5
+)
+
 type postService struct {
 	repo      repository.PostRepository
 	redis     *redis.Client
-	publisher *event.Publisher
+	publisher event.EventPublisher
+	timeline  Timeline
+	blob      storage.Blob
+	urlTTL    time.Duration
 }
 
-func NewPostService(repo repository.PostRepository, redis *redis.Client, publisher *event.Publisher) PostService {
-	return &postService{repo: repo, redis: redis, publisher: publisher}
+func NewPostService(repo repository.PostRepository, redis *redis.Client, publisher event.EventPublisher, blob storage.Blob, urlTTL time.Duration) PostService {
+	return &postService{
+		repo:      repo,
+		redis:     redis,
+		publisher: publisher,
+		timeline:  NewReadTimeline(repo),
+		blob:      blob,
+		urlTTL:    urlTTL,
+	}
 }
 
 func (s *postService) Create(ctx context.Context, authorID int64, username, avatarURL string, req *dto.CreatePostRequest) (*domain.Post, error) {
+	status := req.Status
+	if status == "" {
+		status = domain.PostStatusPublished
+	}
+	body, err := render.Render(req.ContentFormat, req.Content)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachmentsOf(ctx, authorID, req.Attachments)
+	if err != nil {
+		return nil, err
+	}
 	post := &domain.Post{
 		Title:           req.Title,
-		Content:         req.Content,
+		Mentions:        mentionsOf(username, req.Title, body.Text),
+		Content:         body.Source,
+		ContentFormat:   body.Format,
+		ContentHTML:     body.HTML,
+		ContentText:     body.Text,
+		Excerpt:         body.Excerpt,
 		AuthorID:        authorID,
 		AuthorUsername:  username,
 		AuthorAvatarURL: avatarURL,
-		Tags:            req.Tags,
+		Tags:            util.MergeTags(req.Tags, util.ExtractHashtags(req.Title, body.Text)),
+		Attachments:     attachments,
+		Status:          status,
 	}
 	id, err := s.repo.CreatePost(ctx, post)
 	if err != nil {
 		return nil, err
 	}
-	s.publishEvent(ctx, "PostCreated", map[string]interface{}{
-		"post_id":   id,
-		"author_id": authorID,
-		"title":     post.Title,
-	})
+	postsCreated.Inc()
+	s.redis.Del(ctx, authorStatsKey(authorID))
+	// A draft is announced when it gets published (see UpdatePost)
+	if post.Status == domain.PostStatusPublished {
+		s.publishEvent(ctx, event.PostCreated{
+			PostID:   id,
+			AuthorID: authorID,
+			Mentions: post.Mentions,
+			Post:     event.NewPostSnapshot(post),
+		})
+	}
+	s.signAttachments(ctx, post)
 	return post, nil
 }
 
 func (s *postService) GetPost(ctx context.Context, id int64, userID int64) (*domain.Post, error) {
-	post, err := s.repo.GetPost(ctx, id)
+	post, err := s.visiblePost(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
 	if userID != 0 {
-		post.IsLikedByMe, _ = s.repo.HasLiked(ctx, id, userID)
+		liked, bookmarked := s.viewerFlags(ctx, userID, []int64{id})
+		post.IsLikedByMe = liked[id]
+		post.IsBookmarkedByMe = bookmarked[id]
 	}
+	s.signAttachments(ctx, post)
 	return post, nil
 }
 
-func (s *postService) ListPosts(ctx context.Context, q dto.ListPostsQuery, userID int64) ([]domain.Post, error) {
-	posts, err := s.repo.ListPostsFiltered(ctx, q.Limit, q.Offset, q.Sort, q.Author, q.Tag)
+func (s *postService) ListPosts(ctx context.Context, q dto.ListPostsQuery, userID int64) ([]domain.PostListItem, error) {
+	// Only the top sort is ranked within a period
+	if _, ok := repository.TopPeriods[q.Period]; q.Sort == "top" && !ok {
+		return nil, fmt.Errorf("invalid period")
+	}
+	if q.Sort == "hot" {
+		defer prometheus.NewTimer(hotFeedDuration).ObserveDuration()
+	}
+	posts, err := s.repo.ListPostsFiltered(ctx, q.Limit, q.Offset, q.Sort, q.Period, q.Author, q.Tag, q.IncludeArchived)
 	if err != nil {
 		return nil, err
 	}
-	// We set IsLikedByMe if the user is authorized
-	if userID != 0 {
-		for i := range posts {
-			posts[i].IsLikedByMe, _ = s.repo.HasLiked(ctx, posts[i].ID, userID)
+	markViewerFlags(ctx, s, posts, userID, listItemFlags)
+	return posts, nil
+}
+
+func (s *postService) ListAuthorPosts(ctx context.Context, authorID, viewerID int64, q dto.ListAuthorPostsQuery) ([]domain.Post, error) {
+	switch q.Status {
+	case "", domain.PostStatusPublished:
+	case domain.PostStatusDraft, "deleted", "all":
+		// Drafts and deleted posts are visible only to their author
+		if viewerID != authorID {
+			return nil, fmt.Errorf("forbidden")
 		}
+	default:
+		return nil, fmt.Errorf("invalid status")
 	}
+	posts, err := s.repo.ListAuthorPosts(ctx, authorID, q.Status, q.Limit, q.Offset, q.IncludeArchived)
+	if err != nil {
+		return nil, err
+	}
+	markViewerFlags(ctx, s, posts, viewerID, postFlags)
 	return posts, nil
 }
 
-func (s *postService) SearchPosts(ctx context.Context, query string, limit, offset int) ([]domain.Post, error) {
+func (s *postService) GetAuthorStats(ctx context.Context, authorID int64) (*domain.AuthorStats, error) {
+	key := authorStatsKey(authorID)
+	if cached, err := s.redis.Get(ctx, key).Bytes(); err == nil {
+		var stats domain.AuthorStats
+		if json.Unmarshal(cached, &stats) == nil {
+			cacheRequests.WithLabelValues("author_stats", "hit").Inc()
+			return &stats, nil
+		}
+	}
+	cacheRequests.WithLabelValues("author_stats", "miss").Inc()
+
+	// A miss usually follows an invalidation: a lagging replica would cache
+	// the stats from before the change for the whole TTL
+	stats, err := s.repo.GetAuthorStats(repository.WithPrimary(ctx), authorID, authorStatsTopTags)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(stats); err == nil {
+		s.redis.Set(ctx, key, data, authorStatsTTL)
+	}
+	return stats, nil
+}
+
+// InvalidateAuthorStats drops the cached stats after the posts of the author
+// changed outside this service, e.g. on a ban or an account deletion.
+func (s *postService) InvalidateAuthorStats(ctx context.Context, authorID int64) {
+	s.redis.Del(ctx, authorStatsKey(authorID))
+}
+
+func (s *postService) SearchPosts(ctx context.Context, query string, limit, offset int, includeArchived bool, userID int64) ([]domain.PostListItem, error) {
 	if query == "" {
-		return []domain.Post{}, nil
+		return []domain.PostListItem{}, nil
 	}
-	return s.repo.SearchPosts(ctx, query, limit, offset)
+	posts, err := s.repo.SearchPosts(ctx, query, limit, offset, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	markViewerFlags(ctx, s, posts, userID, listItemFlags)
+	return posts, nil
 }
 
 func (s *postService) UpdatePost(ctx context.Context, id, userID int64, req *dto.UpdatePostRequest) (*domain.Post, error) {
+	// The checks before a write must not see a lagging replica
+	ctx = repository.WithPrimary(ctx)
 	existing, err := s.repo.GetPost(ctx, id)
 	if err != nil {
 		return nil, err
@@ -95,19 +213,110 @@ func (s *postService) UpdatePost(ctx context.Context, id, userID int64, req *dto
 	if existing.AuthorID != userID {
 		return nil, fmt.Errorf("forbidden")
 	}
-	post, err := s.repo.UpdatePost(ctx, id, req.Title, req.Content, req.Tags)
+	if existing.Archived {
+		return nil, fmt.Errorf("post is archived")
+	}
+	// Posts of a banned author stay hidden until the ban is lifted
+	if existing.Status == domain.PostStatusHidden && req.Status != nil {
+		return nil, fmt.Errorf("post is hidden")
+	}
+	// Consumers know a post from its PostCreated: publishing is one-way, so the
+	// event is sent once
+	if existing.Status == domain.PostStatusPublished && req.Status != nil && *req.Status == domain.PostStatusDraft {
+		return nil, fmt.Errorf("post is published")
+	}
+	// Re-render the body when either the source or its format changes
+	var body *render.Content
+	if req.Content != nil || req.ContentFormat != nil {
+		source, format := existing.Content, existing.ContentFormat
+		if req.Content != nil {
+			source = *req.Content
+		}
+		if req.ContentFormat != nil {
+			format = *req.ContentFormat
+		}
+		rendered, err := render.Render(format, source)
+		if err != nil {
+			return nil, err
+		}
+		body = &rendered
+	}
+	attachments, err := s.attachmentsOf(ctx, userID, req.Attachments)
 	if err != nil {
 		return nil, err
 	}
-	s.redis.Del(ctx, fmt.Sprintf("post:%d", id))
-	s.publishEvent(ctx, "PostUpdated", map[string]interface{}{
-		"post_id":   id,
-		"author_id": userID,
-	})
+	upd := repository.PostUpdate{
+		Title:       req.Title,
+		Body:        body,
+		Status:      req.Status,
+		Attachments: attachments,
+	}
+
+	// Hashtags and mentions follow the title and the text of the post
+	addedMentions := []string{}
+	if req.Title != nil || body != nil || req.Tags != nil {
+		title, text := existing.Title, ""
+		if req.Title != nil {
+			title = *req.Title
+		}
+		if body != nil {
+			text = body.Text
+		} else if current, err := render.Render(existing.ContentFormat, existing.Content); err == nil {
+			text = current.Text
+		}
+		tags := existing.Tags
+		if req.Tags != nil {
+			tags = req.Tags
+		}
+		upd.Tags = util.MergeTags(tags, util.ExtractHashtags(title, text))
+		if req.Title != nil || body != nil {
+			upd.Mentions = mentionsOf(existing.AuthorUsername, title, text)
+			addedMentions = newMentions(existing.Mentions, upd.Mentions)
+		}
+	}
+
+	// Uploads no longer referenced by the post, cleaned up once the update is stored
+	var removedKeys []string
+	if attachments != nil {
+		if removedKeys, err = s.releasedKeys(ctx, existing, attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	post, err := s.repo.UpdatePost(ctx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	postsUpdated.Inc()
+	s.redis.Del(ctx, fmt.Sprintf("post:%d", id), authorStatsKey(userID))
+	switch {
+	case post.Status == domain.PostStatusPublished && existing.Status == domain.PostStatusDraft:
+		// The draft goes live: consumers see it for the first time
+		s.publishEvent(ctx, event.PostCreated{
+			PostID:   id,
+			AuthorID: userID,
+			Mentions: post.Mentions,
+			Post:     event.NewPostSnapshot(post),
+		})
+	case post.Status == domain.PostStatusDraft && existing.Status == domain.PostStatusDraft:
+		// Nothing was announced yet
+	default:
+		s.publishEvent(ctx, event.PostUpdated{
+			PostID:   id,
+			AuthorID: userID,
+			Mentions: addedMentions,
+			Post:     event.NewPostSnapshot(post),
+		})
+	}
+	if len(removedKeys) > 0 {
+		s.publishEvent(ctx, event.PostAttachmentsRemoved{PostID: id, AuthorID: userID, AttachmentKeys: removedKeys})
+	}
+	s.signAttachments(ctx, post)
 	return post, nil
 }
 
 func (s *postService) DeletePost(ctx context.Context, id, userID int64) error {
+	ctx = repository.WithPrimary(ctx)
 	existing, err := s.repo.GetPost(ctx, id)
 	if err != nil {
 		return err
@@ -115,13 +324,24 @@ func (s *postService) DeletePost(ctx context.Context, id, userID int64) error {
 	if existing.AuthorID != userID {
 		return fmt.Errorf("forbidden")
 	}
+	if existing.Archived {
+		return fmt.Errorf("post is archived")
+	}
 	if err := s.repo.DeletePost(ctx, id); err != nil {
 		return err
 	}
-	s.redis.Del(ctx, fmt.Sprintf("post:%d", id))
-	s.publishEvent(ctx, "PostDeleted", map[string]interface{}{
-		"post_id":   id,
-		"author_id": userID,
+	postsDeleted.Inc()
+	s.redis.Del(ctx, fmt.Sprintf("post:%d", id), authorStatsKey(userID))
+	// The media service removes the files by their storage keys
+	keys, err := s.releasedKeys(ctx, existing, nil)
+	if err != nil {
+		return err
+	}
+	s.publishEvent(ctx, event.PostDeleted{
+		PostID:         id,
+		AuthorID:       userID,
+		AttachmentKeys: keys,
+		Post:           event.NewPostSnapshot(existing),
 	})
 	return nil
 }
@@ -129,13 +349,25 @@ func (s *postService) DeletePost(ctx context.Context, id, userID int64) error {
 func (s *postService) IncrementView(ctx context.Context, postID, userID int64) error {
 	key := fmt.Sprintf("post:%d:viewed_by:%d", postID, userID)
 	set, err := s.redis.SetNX(ctx, key, "1", 24*time.Hour).Result()
-	if err != nil || !set {
+	if err != nil {
 		return err
 	}
-	return s.repo.IncrementView(ctx, postID)
+	if !set {
+		views.WithLabelValues("deduplicated").Inc()
+		return nil
+	}
+	if err := s.repo.IncrementView(ctx, postID); err != nil {
+		return err
+	}
+	views.WithLabelValues("counted").Inc()
+	return nil
 }
 
 func (s *postService) Like(ctx context.Context, postID, userID int64) error {
+	ctx = repository.WithPrimary(ctx)
+	if _, err := s.visiblePost(ctx, postID, userID); err != nil {
+		return err
+	}
 	hasLiked, err := s.repo.HasLiked(ctx, postID, userID)
 	if err != nil {
 		return err
@@ -149,14 +381,17 @@ func (s *postService) Like(ctx context.Context, postID, userID int64) error {
 	if err := s.repo.IncrementLike(ctx, postID); err != nil {
 		return err
 	}
-	s.publishEvent(ctx, "PostLiked", map[string]interface{}{
-		"post_id": postID,
-		"user_id": userID,
-	})
+	likes.Inc()
+	s.publishEvent(ctx, event.PostLiked{PostID: postID, UserID: userID})
 	return nil
 }
 
 func (s *postService) Unlike(ctx context.Context, postID, userID int64) error {
+	ctx = repository.WithPrimary(ctx)
+	// Likes carry no foreign key since the archive (0011), so the post is checked here
+	if _, err := s.repo.GetPost(ctx, postID); err != nil {
+		return err
+	}
 	hasLiked, err := s.repo.HasLiked(ctx, postID, userID)
 	if err != nil {
 		return err
@@ -170,14 +405,304 @@ func (s *postService) Unlike(ctx context.Context, postID, userID int64) error {
 	if err := s.repo.DecrementLike(ctx, postID); err != nil {
 		return err
 	}
-	s.publishEvent(ctx, "PostUnliked", map[string]interface{}{
-		"post_id": postID,
-		"user_id": userID,
-	})
+	unlikes.Inc()
+	s.publishEvent(ctx, event.PostUnliked{PostID: postID, UserID: userID})
+	return nil
+}
+
+func (s *postService) ListLikedPosts(ctx context.Context, userID int64, cursor string, limit int) (*dto.Page[domain.LikedPost], error) {
+	before, err := util.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	// One extra row tells us whether there is a next page
+	posts, err := s.repo.ListLikedPosts(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[domain.LikedPost]{Items: posts}
+	if len(posts) > limit {
+		page.Items = posts[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = util.EncodeCursor(last.LikedAt, last.ID)
+	}
+	ids := make([]int64, len(page.Items))
+	for i := range page.Items {
+		ids[i] = page.Items[i].ID
+	}
+	_, bookmarked := s.viewerFlags(ctx, userID, ids)
+	for i := range page.Items {
+		page.Items[i].IsLikedByMe = true
+		page.Items[i].IsBookmarkedByMe = bookmarked[page.Items[i].ID]
+	}
+	return page, nil
+}
+
+func (s *postService) ListPostLikers(ctx context.Context, postID, viewerID int64, cursor string, limit int) (*dto.PostLikersResponse, error) {
+	before, err := util.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.visiblePost(ctx, postID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	likes, err := s.repo.ListPostLikers(ctx, postID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.PostLikersResponse{PostID: postID, Count: post.LikesCount, Users: likes}
+	if len(likes) > limit {
+		resp.Users = likes[:limit]
+		last := resp.Users[limit-1]
+		resp.NextCursor = util.EncodeCursor(last.LikedAt, last.UserID)
+	}
+	return resp, nil
+}
+
+func (s *postService) Bookmark(ctx context.Context, postID, userID int64, folder string) error {
+	ctx = repository.WithPrimary(ctx)
+	if _, err := s.visiblePost(ctx, postID, userID); err != nil {
+		return err
+	}
+	if err := s.repo.AddBookmark(ctx, postID, userID, folder); err != nil {
+		return err
+	}
+	s.publishEvent(ctx, event.PostBookmarked{PostID: postID, UserID: userID, Folder: folder})
 	return nil
 }
 
-func (s *postService) publishEvent(ctx context.Context, eventType string, payload map[string]interface{}) {
-	payload["event"] = eventType
-	s.publisher.Publish(ctx, "post_events", payload)
+func (s *postService) Unbookmark(ctx context.Context, postID, userID int64) error {
+	if err := s.repo.RemoveBookmark(ctx, postID, userID); err != nil {
+		return err
+	}
+	s.publishEvent(ctx, event.PostUnbookmarked{PostID: postID, UserID: userID})
+	return nil
+}
+
+func (s *postService) ListBookmarks(ctx context.Context, userID int64, folder *string, cursor string, limit int) (*dto.Page[domain.BookmarkedPost], error) {
+	before, err := util.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.repo.ListBookmarks(ctx, userID, folder, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[domain.BookmarkedPost]{Items: posts}
+	if len(posts) > limit {
+		page.Items = posts[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = util.EncodeCursor(last.BookmarkedAt, last.ID)
+	}
+	ids := make([]int64, len(page.Items))
+	for i := range page.Items {
+		ids[i] = page.Items[i].ID
+	}
+	liked, _ := s.viewerFlags(ctx, userID, ids)
+	for i := range page.Items {
+		page.Items[i].IsLikedByMe = liked[page.Items[i].ID]
+		page.Items[i].IsBookmarkedByMe = true
+	}
+	return page, nil
+}
+
+func (s *postService) Feed(ctx context.Context, userID int64, cursor string, limit int) (*dto.Page[domain.PostListItem], error) {
+	before, err := util.DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := s.timeline.Page(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.Page[domain.PostListItem]{Items: posts}
+	if len(posts) > limit {
+		page.Items = posts[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = util.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	markViewerFlags(ctx, s, page.Items, userID, listItemFlags)
+	return page, nil
+}
+
+// visiblePost loads a post the user may see: a published one, or any of their own.
+func (s *postService) visiblePost(ctx context.Context, id, userID int64) (*domain.Post, error) {
+	post, err := s.repo.GetPost(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.Status != domain.PostStatusPublished && post.AuthorID != userID {
+		return nil, fmt.Errorf("post not found")
+	}
+	return post, nil
+}
+
+// markViewerFlags sets IsLikedByMe and IsBookmarkedByMe for an authorized user.
+// flags exposes the ID and the flag fields of a post representation.
+func markViewerFlags[T any](ctx context.Context, s *postService, posts []T, userID int64, flags func(*T) (int64, *bool, *bool)) {
+	if userID == 0 || len(posts) == 0 {
+		return
+	}
+	ids := make([]int64, len(posts))
+	for i := range posts {
+		ids[i], _, _ = flags(&posts[i])
+	}
+	liked, bookmarked := s.viewerFlags(ctx, userID, ids)
+	for i := range posts {
+		id, isLiked, isBookmarked := flags(&posts[i])
+		*isLiked = liked[id]
+		*isBookmarked = bookmarked[id]
+	}
+}
+
+func postFlags(p *domain.Post) (int64, *bool, *bool) {
+	return p.ID, &p.IsLikedByMe, &p.IsBookmarkedByMe
+}
+
+func listItemFlags(p *domain.PostListItem) (int64, *bool, *bool) {
+	return p.ID, &p.IsLikedByMe, &p.IsBookmarkedByMe
+}
+
+// viewerFlags resolves likes and bookmarks of a page of posts with one query each.
+// Lookup errors are not fatal for reads: the flags just stay false.
+func (s *postService) viewerFlags(ctx context.Context, userID int64, ids []int64) (liked, bookmarked map[int64]bool) {
+	liked, err := s.repo.LikedPostIDs(ctx, userID, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "liked flags", "err", err)
+		liked = map[int64]bool{}
+	}
+	bookmarked, err = s.repo.BookmarkedPostIDs(ctx, userID, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "bookmarked flags", "err", err)
+		bookmarked = map[int64]bool{}
+	}
+	return liked, bookmarked
+}
+
+// signAttachments replaces the stored URLs of uploaded attachments with fresh
+// signed ones: the URL returned by the upload expires. Only the uploads of the
+// author are signed.
+func (s *postService) signAttachments(ctx context.Context, post *domain.Post) {
+	owned, err := s.ownedKeys(ctx, post.AuthorID, post.Attachments)
+	if err != nil {
+		slog.WarnContext(ctx, "attachment owners", "err", err)
+		return
+	}
+	for i := range post.Attachments {
+		a := &post.Attachments[i]
+		if !slices.Contains(owned, a.Key) {
+			continue
+		}
+		if url, err := s.blob.SignedURL(ctx, a.Key, s.urlTTL); err == nil {
+			a.URL = url
+		}
+	}
+}
+
+// mentionsOf extracts @mentions of texts, without the author mentioning themselves.
+func mentionsOf(author string, texts ...string) []string {
+	mentions := make([]string, 0)
+	for _, m := range util.ExtractMentions(texts...) {
+		if !strings.EqualFold(m, author) {
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions
+}
+
+// newMentions returns the usernames of current that were not mentioned before.
+// Usernames are case-insensitive: @Bob and @bob mention the same user.
+func newMentions(before, current []string) []string {
+	old := make(map[string]bool, len(before))
+	for _, m := range before {
+		old[strings.ToLower(m)] = true
+	}
+	added := make([]string, 0)
+	for _, m := range current {
+		if !old[strings.ToLower(m)] {
+			added = append(added, m)
+		}
+	}
+	return added
+}
+
+// attachmentsOf converts the attachments of a request by authorID. A storage key
+// the author did not upload is dropped, the attachment keeps only its URL.
+func (s *postService) attachmentsOf(ctx context.Context, authorID int64, in []dto.AttachmentInput) ([]domain.Attachment, error) {
+	attachments := toAttachments(in)
+	owned, err := s.ownedKeys(ctx, authorID, attachments)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		if !slices.Contains(owned, attachments[i].Key) {
+			attachments[i].Key = ""
+		}
+	}
+	return attachments, nil
+}
+
+// ownedKeys returns the storage keys of attachments uploaded by authorID.
+func (s *postService) ownedKeys(ctx context.Context, authorID int64, attachments []domain.Attachment) ([]string, error) {
+	keys := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		if a.Key != "" {
+			keys = append(keys, a.Key)
+		}
+	}
+	owned, err := s.repo.OwnedUploadKeys(ctx, authorID, keys)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(key string) bool { return !owned[key] }), nil
+}
+
+// releasedKeys returns the storage keys post stops using when its attachments
+// become keep: uploads of its author that no other post references.
+func (s *postService) releasedKeys(ctx context.Context, post *domain.Post, keep []domain.Attachment) ([]string, error) {
+	keys, err := s.ownedKeys(ctx, post.AuthorID, post.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return slices.ContainsFunc(keep, func(a domain.Attachment) bool { return a.Key == key })
+	})
+	inUse, err := s.repo.AttachmentKeysInUse(ctx, post.ID, keys)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(key string) bool { return inUse[key] }), nil
+}
+
+// toAttachments keeps nil as nil: for updates it means "attachments unchanged".
+func toAttachments(in []dto.AttachmentInput) []domain.Attachment {
+	if in == nil {
+		return nil
+	}
+	out := make([]domain.Attachment, len(in))
+	for i, a := range in {
+		out[i] = domain.Attachment{
+			URL:       a.URL,
+			Key:       a.Key,
+			MimeType:  a.MimeType,
+			SizeBytes: a.SizeBytes,
+			Width:     a.Width,
+			Height:    a.Height,
+			AltText:   a.AltText,
+		}
+	}
+	return out
+}
+
+func authorStatsKey(authorID int64) string {
+	return fmt.Sprintf("author:%d:stats", authorID)
+}
+
+func (s *postService) publishEvent(ctx context.Context, evt event.Event) {
+	s.publisher.Publish(ctx, evt)
 }
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	if got := mentionsOf("Alice", "hi @alice and @Bob", "cc @carol"); !slices.Equal(got, []string{"Bob", "carol"}) {
+		t.Errorf("mentionsOf = %v, want [Bob carol]", got)
+	}
+
+	tests := []struct {
+		name            string
+		before, current []string
+		want            []string
+	}{
+		{"first mentions", nil, []string{"bob"}, []string{"bob"}},
+		{"unchanged", []string{"bob"}, []string{"bob"}, []string{}},
+		{"another spelling of the same user", []string{"bob"}, []string{"Bob", "CAROL"}, []string{"CAROL"}},
+		{"removed mentions are not new", []string{"Bob", "carol"}, []string{"carol"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newMentions(tt.before, tt.current); !slices.Equal(got, tt.want) {
+				t.Errorf("newMentions(%v, %v) = %v, want %v", tt.before, tt.current, got, tt.want)
+			}
+		})
+	}
+}
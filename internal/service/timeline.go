@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"post-service/internal/domain"
+	"post-service/internal/repository"
+	"post-service/internal/util"
+)
+
+// Timeline serves the "following" feed of a user, newest first.
+// readTimeline builds it on every request from the follow tables (fan-out-on-read);
+// a cached implementation (e.g. a Redis sorted set per follower) can replace it
+// without touching the service.
+type Timeline interface {
+	Page(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.PostListItem, error)
+}
+
+type readTimeline struct {
+	repo repository.PostRepository
+}
+
+func NewReadTimeline(repo repository.PostRepository) Timeline {
+	return &readTimeline{repo: repo}
+}
+
+func (t *readTimeline) Page(ctx context.Context, userID int64, before *util.Cursor, limit int) ([]domain.PostListItem, error) {
+	return t.repo.ListFeed(ctx, userID, before, limit)
+}
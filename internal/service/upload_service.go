@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"post-service/internal/dto"
+	"post-service/internal/repository"
+	"post-service/internal/storage"
+	"time"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Sniffed content type -> key extension
+var uploadMimeTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type UploadService interface {
+	Upload(ctx context.Context, uploaderID int64, r io.Reader) (*dto.UploadResponse, error)
+}
+
+type uploadService struct {
+	repo     repository.PostRepository
+	blob     storage.Blob
+	maxBytes int64
+	urlTTL   time.Duration
+}
+
+func NewUploadService(repo repository.PostRepository, blob storage.Blob, maxBytes int64, urlTTL time.Duration) UploadService {
+	return &uploadService{repo: repo, blob: blob, maxBytes: maxBytes, urlTTL: urlTTL}
+}
+
+func (s *uploadService) Upload(ctx context.Context, uploaderID int64, r io.Reader) (*dto.UploadResponse, error) {
+	// Read one byte more than allowed to detect oversized files
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return nil, fmt.Errorf("file too large")
+	}
+
+	// The declared Content-Type of the part is not trusted
+	mimeType := http.DetectContentType(data)
+	ext, ok := uploadMimeTypes[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported media type")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported media type")
+	}
+
+	// Content addressed keys within the uploads of a user: the same file
+	// uploaded twice by them is stored once, and never shared with another user
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	key := fmt.Sprintf("uploads/%d/%s/%s%s", uploaderID, hash[:2], hash, ext)
+
+	exists, err := s.blob.Exists(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := s.blob.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mimeType); err != nil {
+			return nil, err
+		}
+	}
+	// Posts of this uploader may now reference the key
+	if err := s.repo.AddUpload(ctx, key, uploaderID); err != nil {
+		// Nothing could ever reference a blob stored just now: remove it
+		if !exists {
+			if delErr := s.blob.Delete(context.WithoutCancel(ctx), key); delErr != nil {
+				slog.ErrorContext(ctx, "delete orphaned upload", "key", key, "err", delErr)
+			}
+		}
+		return nil, err
+	}
+
+	url, err := s.blob.SignedURL(ctx, key, s.urlTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.UploadResponse{
+		Key:       key,
+		URL:       url,
+		ExpiresAt: time.Now().Add(s.urlTTL).UTC(),
+		MimeType:  mimeType,
+		SizeBytes: int64(len(data)),
+		Width:     cfg.Width,
+		Height:    cfg.Height,
+		SHA256:    hash,
+	}, nil
+}
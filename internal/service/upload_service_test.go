@@ -0,0 +1,48 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"path/filepath"
+	"post-service/internal/repository"
+	"post-service/internal/storage"
+	"testing"
+	"time"
+)
+
+// uploadRepo fails to record uploads; other methods panic through the nil
+// embedded interface.
+type uploadRepo struct {
+	repository.PostRepository
+}
+
+func (uploadRepo) AddUpload(context.Context, string, int64) error {
+	return errors.New("connection refused")
+}
+
+func TestUploadRemovesUnrecordedBlob(t *testing.T) {
+	dir := t.TempDir()
+	blob, err := storage.NewLocal(dir, "http://localhost:8083/api/v1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewUploadService(uploadRepo{}, blob, 1<<20, time.Minute)
+	if _, err := svc.Upload(context.Background(), 7, &img); err == nil {
+		t.Fatal("expected the repository error")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "uploads", "7", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("blobs left behind: %v", files)
+	}
+}
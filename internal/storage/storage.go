@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Blob is an object store for uploaded files addressed by key.
+type Blob interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a download URL that stops working after ttl.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+type Config struct {
+	Backend string // local, s3
+
+	LocalDir   string
+	PublicURL  string // base URL of the signed download route of the local backend
+	SigningKey string
+
+	S3Endpoint  string
+	S3AccessKey string
+	S3SecretKey string
+	S3Bucket    string
+	S3Region    string
+	S3UseSSL    bool
+}
+
+func New(ctx context.Context, cfg Config) (Blob, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocal(cfg.LocalDir, cfg.PublicURL, cfg.SigningKey)
+	case "s3":
+		return NewS3(ctx, cfg.S3Endpoint, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Bucket, cfg.S3Region, cfg.S3UseSSL)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
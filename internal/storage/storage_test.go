@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBlob runs the contract every backend must meet.
+func testBlob(t *testing.T, blob Blob) {
+	ctx := context.Background()
+	key := fmt.Sprintf("uploads/%d/ab/abcdef.png", time.Now().UnixNano())
+	body := "not really a png"
+
+	exists, err := blob.Exists(ctx, key)
+	if err != nil || exists {
+		t.Fatalf("Exists before Put = %v, %v", exists, err)
+	}
+	if err := blob.Put(ctx, key, strings.NewReader(body), int64(len(body)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if exists, err := blob.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("Exists after Put = %v, %v", exists, err)
+	}
+	// Putting the same key again replaces the blob
+	if err := blob.Put(ctx, key, strings.NewReader(body), int64(len(body)), "image/png"); err != nil {
+		t.Fatalf("second Put: %v", err)
+	}
+
+	signed, err := blob.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !strings.Contains(signed, key) {
+		t.Errorf("SignedURL %q does not address %s", signed, key)
+	}
+
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if exists, err := blob.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("Exists after Delete = %v, %v", exists, err)
+	}
+	if err := blob.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080/api/v1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlob(t, l)
+}
+
+func TestLocalKeysStayInDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "blobs")
+	l, err := NewLocal(dir, "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../escaped", "uploads/../../escaped", "/../escaped"} {
+		if err := l.Put(ctx, key, strings.NewReader("x"), 1, ""); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "escaped")); err == nil {
+			t.Fatalf("Put %q wrote outside of the storage dir", key)
+		}
+	}
+	for _, key := range []string{"", "/", ".."} {
+		if err := l.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put %q: no error", key)
+		}
+	}
+
+	f, err := l.Open("uploads/../escaped")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer f.Close()
+	if data, _ := io.ReadAll(f); string(data) != "x" {
+		t.Errorf("Open read %q", data)
+	}
+}
+
+func TestLocalVerify(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080/api/v1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "uploads/1/ab/abcdef.png"
+	signed, err := l.SignedURL(context.Background(), key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/v1/"+key {
+		t.Errorf("path = %s", u.Path)
+	}
+	expires, sig := u.Query().Get("expires"), u.Query().Get("sig")
+
+	expired := l.sign(key, time.Now().Add(-time.Second).Unix())
+	other, _ := NewLocal(t.TempDir(), "", "other secret")
+	tests := []struct {
+		name         string
+		local        *Local
+		key, expires string
+		sig          string
+		want         bool
+	}{
+		{"valid", l, key, expires, sig, true},
+		{"other key", l, "uploads/2/ab/abcdef.png", expires, sig, false},
+		{"tampered expiry", l, key, fmt.Sprint(time.Now().Add(time.Hour).Unix()), sig, false},
+		{"expired", l, key, fmt.Sprint(time.Now().Add(-time.Second).Unix()), expired, false},
+		{"bad expiry", l, key, "soon", sig, false},
+		{"empty signature", l, key, expires, "", false},
+		{"other signing key", other, key, expires, sig, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.local.Verify(tt.key, tt.expires, tt.sig); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestS3 runs against the S3 service at TEST_S3_ENDPOINT, e.g. the MinIO of
+// docker-compose.yml: TEST_S3_ENDPOINT=localhost:9000.
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+	accessKey, secretKey := os.Getenv("TEST_S3_ACCESS_KEY"), os.Getenv("TEST_S3_SECRET_KEY")
+	if accessKey == "" {
+		accessKey, secretKey = "minioadmin", "minioadmin"
+	}
+	ctx := context.Background()
+	s3, err := NewS3(ctx, endpoint, accessKey, secretKey, "post-service-test", "us-east-1", false)
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	testBlob(t, s3)
+
+	// The presigned URL serves the object without credentials
+	key := fmt.Sprintf("uploads/%d/cd/cdef.png", time.Now().UnixNano())
+	if err := s3.Put(ctx, key, strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s3.Delete(ctx, key) })
+	signed, err := s3.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(data) != "png" || resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("GET signed URL = %d %q %s", resp.StatusCode, data, resp.Header.Get("Content-Type"))
+	}
+}
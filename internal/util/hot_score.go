@@ -1,7 +1,88 @@
 package util
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
-func CalculateHotScore(likes, comments, views int64) float64 {
-	return float64(likes)*0.8 + float64(comments)*0.5 + math.Log(float64(views)+1)
+// HotWeights are the coefficients of the hot formulas, loaded from config.
+type HotWeights struct {
+	Likes    float64
+	Comments float64
+	Views    float64
+	Gravity  float64
+}
+
+func CalculateHotScore(likes, comments, views int64, w HotWeights) float64 {
+	return float64(likes)*w.Likes + float64(comments)*w.Comments + w.Views*math.Log(float64(views)+1)
+}
+
+// HotRanking is a hot formula available both in Go (Score) and in SQL
+// (the ListPostsHot* queries in sqlc/query.sql, picked by Name), so that scores
+// computed in memory match the ORDER BY of list queries.
+type HotRanking interface {
+	Name() string
+	Score(likes, comments, views int64, createdAt, now time.Time) float64
+	// Weights are the arguments of the SQL formula.
+	Weights() HotWeights
+}
+
+const (
+	HotRankingLinear = "linear"
+	HotRankingReddit = "reddit"
+	HotRankingHN     = "hn"
+)
+
+func NewHotRanking(name string, w HotWeights) (HotRanking, error) {
+	switch name {
+	case "", HotRankingLinear:
+		return linearRanking{w: w}, nil
+	case HotRankingReddit:
+		return redditRanking{w: w}, nil
+	case HotRankingHN:
+		return hnRanking{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown hot ranking %q", name)
+}
+
+// linearRanking — engagement without time decay (the original formula).
+type linearRanking struct{ w HotWeights }
+
+func (linearRanking) Name() string { return HotRankingLinear }
+
+func (r linearRanking) Weights() HotWeights { return r.w }
+
+func (r linearRanking) Score(likes, comments, views int64, _, _ time.Time) float64 {
+	return CalculateHotScore(likes, comments, views, r.w)
+}
+
+// redditRanking — log10 of engagement plus a linear bonus for newer posts:
+// 10x the engagement is worth 12.5 hours.
+type redditRanking struct{ w HotWeights }
+
+const (
+	redditEpoch  = 1134028003
+	redditPeriod = 45000
+)
+
+func (redditRanking) Name() string { return HotRankingReddit }
+
+func (r redditRanking) Weights() HotWeights { return r.w }
+
+func (r redditRanking) Score(likes, comments, _ int64, createdAt, _ time.Time) float64 {
+	engagement := float64(likes)*r.w.Likes + float64(comments)*r.w.Comments
+	return math.Log10(math.Max(engagement, 1)) + float64(createdAt.Unix()-redditEpoch)/redditPeriod
+}
+
+// hnRanking — Hacker News gravity: points / (age_hours + 2)^gravity.
+type hnRanking struct{ w HotWeights }
+
+func (hnRanking) Name() string { return HotRankingHN }
+
+func (r hnRanking) Weights() HotWeights { return r.w }
+
+func (r hnRanking) Score(likes, comments, views int64, createdAt, now time.Time) float64 {
+	hours := math.Max(now.Sub(createdAt).Hours(), 0)
+	return CalculateHotScore(likes, comments, views, r.w) / math.Pow(hours+2, r.w.Gravity)
 }
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bounds of usernames, and the longest tag that fits tag_follows.tag
+const (
+	minUsernameLength = 2
+	maxUsernameLength = 32
+	MaxTagLength      = 100
+)
+
+var (
+	// @username not preceded by a word character (so e-mails do not match);
+	// the length is checked on the whole name, so a longer one is not cut
+	mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]+)`)
+	// #hashtag not preceded by a word character or & (HTML entities like &#39;)
+	hashtagRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#&])#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+)
+
+// ExtractMentions returns the unique @usernames of texts in order of appearance.
+func ExtractMentions(texts ...string) []string {
+	return extract(mentionRe, texts, func(s string) string {
+		if len(s) < minUsernameLength || len(s) > maxUsernameLength {
+			return ""
+		}
+		return s
+	})
+}
+
+// ExtractHashtags returns the unique normalized #hashtags of texts; hashtags
+// longer than MaxTagLength are skipped.
+func ExtractHashtags(texts ...string) []string {
+	return extract(hashtagRe, texts, func(s string) string {
+		if s = NormalizeTag(s); utf8.RuneCountInString(s) > MaxTagLength {
+			return ""
+		}
+		return s
+	})
+}
+
+func extract(re *regexp.Regexp, texts []string, norm func(string) string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, text := range texts {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			v := norm(m[1])
+			if k := strings.ToLower(v); v != "" && !seen[k] {
+				seen[k] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// NormalizeTag lowercases a tag and strips the leading # and surrounding spaces.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimSpace(tag)
+	tag = strings.TrimLeft(tag, "#")
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// MergeTags normalizes and deduplicates tags keeping the first occurrence order.
+func MergeTags(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, list := range lists {
+		for _, t := range list {
+			if t = NormalizeTag(t); t != "" && !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
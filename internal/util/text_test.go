@@ -0,0 +1,87 @@
+package util
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"simple", "learning #go today", []string{"go"}},
+		{"start of text", "#Go is fun", []string{"go"}},
+		{"lowercased and deduplicated", "#Go #GO #go #rust", []string{"go", "rust"}},
+		{"unicode letters", "#Привет #café", []string{"привет", "café"}},
+		{"digits and underscores", "#web_3 #2024plans", []string{"web_3", "2024plans"}},
+		{"only digits is not a tag", "issue #123", []string{}},
+		{"inside a word", "C#sharp and a#b", []string{}},
+		{"html entity", "it&#39;s fine", []string{}},
+		{"double hash", "##go", []string{}},
+		{"punctuation ends the tag", "(#go), #rust.", []string{"go", "rust"}},
+		{"url fragment", "see https://example.com/page#section", []string{}},
+		{"longest tag", "#" + strings.Repeat("a", 100), []string{strings.Repeat("a", 100)}},
+		{"too long tag", "#" + strings.Repeat("я", 101) + " #go", []string{"go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHashtags(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractHashtags(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if got := ExtractHashtags("#go in the title", "and #Go, #news in the text"); !slices.Equal(got, []string{"go", "news"}) {
+		t.Errorf("several texts = %v", got)
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"simple", "thanks @bob!", []string{"bob"}},
+		{"start of text", "@alice look", []string{"alice"}},
+		{"case is kept, first spelling wins", "@Bob and @bob", []string{"Bob"}},
+		{"e-mail is not a mention", "mail bob@example.com", []string{}},
+		{"double at", "@@bob", []string{}},
+		{"too short", "@a", []string{}},
+		{"longest username", "@" + "abcdefghijklmnopqrstuvwxyz012345", []string{"abcdefghijklmnopqrstuvwxyz012345"}},
+		{"too long username is not cut", "@" + "abcdefghijklmnopqrstuvwxyz0123456 @bob", []string{"bob"}},
+		{"after a letter", "x@bob", []string{}},
+		{"after punctuation", "(@bob), @carol.", []string{"bob", "carol"}},
+		{"underscores and digits", "@bob_2", []string{"bob_2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractMentions(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	for in, want := range map[string]string{
+		"Go":         "go",
+		"#Go":        "go",
+		"  #GoLang ": "golang",
+		"##rust":     "rust",
+		"# go":       "go",
+		"#":          "",
+		"ПРИВЕТ":     "привет",
+	} {
+		if got := NormalizeTag(in); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	if got := MergeTags([]string{"Go", "#rust"}, []string{"go", "", "#", "news"}); !slices.Equal(got, []string{"go", "rust", "news"}) {
+		t.Errorf("MergeTags = %v", got)
+	}
+}
@@ -0,0 +1,124 @@
+// Package migration applies the SQL migrations embedded in the binary.
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Migrator wraps golang-migrate. The pgx driver holds a Postgres advisory
+// lock while migrating, so pods starting together apply each version once.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// New connects with a postgres:// URL; versions are kept in schema_migrations.
+func New(databaseURL string) (*Migrator, error) {
+	src, err := iofs.New(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, pgxURL(databaseURL))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{m: m}, nil
+}
+
+// pgxURL switches the scheme to the one the pgx/v5 driver is registered under.
+func pgxURL(u string) string {
+	for _, scheme := range []string{"postgres://", "postgresql://"} {
+		if strings.HasPrefix(u, scheme) {
+			return "pgx5://" + strings.TrimPrefix(u, scheme)
+		}
+	}
+	return u
+}
+
+// Up applies every pending migration.
+func (mg *Migrator) Up() error {
+	return ignoreNoChange(mg.m.Up())
+}
+
+// Down rolls back the last n migrations.
+func (mg *Migrator) Down(n int) error {
+	return ignoreNoChange(mg.m.Steps(-n))
+}
+
+// Force sets the version without running anything, to recover from a dirty state.
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+type Status struct {
+	Version uint
+	Dirty   bool
+	Pending []uint
+}
+
+func (mg *Migrator) Status() (Status, error) {
+	var st Status
+	version, dirty, err := mg.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return st, err
+	}
+	st.Version, st.Dirty = version, dirty
+
+	versions, err := Versions()
+	if err != nil {
+		return st, err
+	}
+	for _, v := range versions {
+		if v > st.Version {
+			st.Pending = append(st.Pending, v)
+		}
+	}
+	return st, nil
+}
+
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// Versions lists the embedded migration versions in order.
+func Versions() ([]uint, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	var versions []uint
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".up.sql")
+		if !ok {
+			continue
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		v, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", e.Name(), err)
+		}
+		versions = append(versions, uint(v))
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestMigrationsArePaired(t *testing.T) {
+	versions, err := Versions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, v := range versions {
+		if v != uint(i+1) {
+			t.Fatalf("versions %v are not contiguous from 1", versions)
+		}
+		down, _ := fs.Glob(files, fmt.Sprintf("migrations/%04d_*.down.sql", v))
+		if len(down) != 1 {
+			t.Errorf("version %d has no down migration", v)
+		}
+	}
+}
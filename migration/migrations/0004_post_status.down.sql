@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_author_created;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS status;
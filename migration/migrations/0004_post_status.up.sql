@@ -0,0 +1,4 @@
+ALTER TABLE posts
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published';
+
+CREATE INDEX idx_posts_author_created ON posts (author_id, created_at DESC);
@@ -0,0 +1 @@
+CREATE INDEX idx_post_likes_user_created ON post_likes (user_id, created_at DESC);
@@ -0,0 +1,10 @@
+CREATE TABLE post_bookmarks (
+    user_id    BIGINT       NOT NULL,
+    post_id    BIGINT       NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    folder     VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ  DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX idx_post_bookmarks_user_created ON post_bookmarks (user_id, created_at DESC);
+CREATE INDEX idx_post_bookmarks_user_folder  ON post_bookmarks (user_id, folder, created_at DESC);
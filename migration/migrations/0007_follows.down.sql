@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_feed;
+DROP TABLE IF EXISTS tag_follows;
+DROP TABLE IF EXISTS user_follows;
@@ -0,0 +1,16 @@
+CREATE TABLE user_follows (
+    follower_id BIGINT      NOT NULL,
+    author_id   BIGINT      NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (follower_id, author_id)
+);
+
+CREATE TABLE tag_follows (
+    follower_id BIGINT       NOT NULL,
+    tag         VARCHAR(100) NOT NULL,
+    created_at  TIMESTAMPTZ  DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (follower_id, tag)
+);
+
+CREATE INDEX idx_posts_feed ON posts (created_at DESC, id DESC)
+    WHERE deleted_at IS NULL AND status = 'published';
@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_posts_search;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (
+            to_tsvector('russian', coalesce(title, '')) ||
+            to_tsvector('russian', coalesce(content, ''))
+        ) STORED;
+CREATE INDEX idx_posts_search ON posts USING GIN(search_vector);
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS excerpt,
+    DROP COLUMN IF EXISTS content_text,
+    DROP COLUMN IF EXISTS content_html,
+    DROP COLUMN IF EXISTS content_format;
@@ -0,0 +1,23 @@
+ALTER TABLE posts
+    ADD COLUMN content_format VARCHAR(20) NOT NULL DEFAULT 'plain',
+    ADD COLUMN content_html   TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN content_text   TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN excerpt        TEXT        NOT NULL DEFAULT '';
+
+-- Existing posts are plain text
+UPDATE posts SET
+    content_text = content,
+    excerpt      = left(regexp_replace(content, '\s+', ' ', 'g'), 280),
+    content_html = '<p>' || replace(replace(replace(replace(content,
+                       '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), E'\n', '<br>') || '</p>';
+
+-- Search indexes the rendered plain text instead of the markup source
+DROP INDEX IF EXISTS idx_posts_search;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (
+            to_tsvector('russian', coalesce(title, '')) ||
+            to_tsvector('russian', coalesce(content_text, ''))
+        ) STORED;
+CREATE INDEX idx_posts_search ON posts USING GIN(search_vector);
@@ -0,0 +1,15 @@
+CREATE TABLE post_attachments (
+    id          BIGSERIAL PRIMARY KEY,
+    post_id     BIGINT       NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    position    INT          NOT NULL DEFAULT 0,
+    url         TEXT         NOT NULL,
+    storage_key TEXT         NOT NULL DEFAULT '',
+    mime_type   VARCHAR(100) NOT NULL,
+    size_bytes  BIGINT       NOT NULL DEFAULT 0,
+    width       INT          NOT NULL DEFAULT 0,
+    height      INT          NOT NULL DEFAULT 0,
+    alt_text    VARCHAR(500) NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ  DEFAULT NOW() NOT NULL
+);
+
+CREATE INDEX idx_post_attachments_post_id ON post_attachments (post_id, position);
@@ -0,0 +1,8 @@
+CREATE TABLE post_mentions (
+    post_id    BIGINT       NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    username   VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ  DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (post_id, username)
+);
+
+CREATE INDEX idx_post_mentions_username ON post_mentions (username);
@@ -0,0 +1,19 @@
+DROP VIEW IF EXISTS all_posts;
+
+INSERT INTO posts (id, title, content, author_id, tags, views, likes_count, comments_count,
+                   created_at, updated_at, deleted_at, author_username, author_avatar_url, status,
+                   content_format, content_html, content_text, excerpt)
+SELECT id, title, content, author_id, tags, views, likes_count, comments_count,
+       created_at, updated_at, deleted_at, author_username, author_avatar_url, status,
+       content_format, content_html, content_text, excerpt
+FROM posts_archive;
+DROP TABLE IF EXISTS posts_archive;
+
+DELETE FROM post_likes       WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_bookmarks   WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_attachments WHERE post_id NOT IN (SELECT id FROM posts);
+DELETE FROM post_mentions    WHERE post_id NOT IN (SELECT id FROM posts);
+ALTER TABLE post_likes       ADD CONSTRAINT post_likes_post_id_fkey       FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+ALTER TABLE post_bookmarks   ADD CONSTRAINT post_bookmarks_post_id_fkey   FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+ALTER TABLE post_attachments ADD CONSTRAINT post_attachments_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
+ALTER TABLE post_mentions    ADD CONSTRAINT post_mentions_post_id_fkey    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
@@ -0,0 +1,32 @@
+-- Old and soft-deleted posts are moved here by the archiver. Columns follow
+-- posts in the same order (search_vector becomes a plain column), so a column
+-- added to posts must be added here too, before archived_at.
+CREATE TABLE posts_archive (LIKE posts INCLUDING DEFAULTS);
+ALTER TABLE posts_archive
+    ADD PRIMARY KEY (id),
+    ADD COLUMN archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX idx_posts_archive_feed           ON posts_archive (created_at DESC, id DESC);
+CREATE INDEX idx_posts_archive_author_created ON posts_archive (author_id, created_at DESC);
+CREATE INDEX idx_posts_archive_search         ON posts_archive USING GIN(search_vector);
+CREATE INDEX idx_posts_archive_tags           ON posts_archive USING GIN(tags);
+
+-- Likes, bookmarks, attachments and mentions stay in place for archived posts
+ALTER TABLE post_likes       DROP CONSTRAINT post_likes_post_id_fkey;
+ALTER TABLE post_bookmarks   DROP CONSTRAINT post_bookmarks_post_id_fkey;
+ALTER TABLE post_attachments DROP CONSTRAINT post_attachments_post_id_fkey;
+ALTER TABLE post_mentions    DROP CONSTRAINT post_mentions_post_id_fkey;
+
+-- Active and archived posts for the queries that read across both
+CREATE VIEW all_posts AS
+    SELECT id, title, content, content_format, content_html, content_text, excerpt,
+           author_id, author_username, author_avatar_url, tags, status,
+           views, likes_count, comments_count, created_at, updated_at, deleted_at,
+           search_vector, FALSE AS archived
+    FROM posts
+    UNION ALL
+    SELECT id, title, content, content_format, content_html, content_text, excerpt,
+           author_id, author_username, author_avatar_url, tags, status,
+           views, likes_count, comments_count, created_at, updated_at, deleted_at,
+           search_vector, TRUE AS archived
+    FROM posts_archive;
@@ -0,0 +1,15 @@
+-- Storage keys issued by POST /uploads; a post may only reference the keys of its author
+CREATE TABLE uploads (
+    storage_key TEXT        NOT NULL,
+    uploader_id BIGINT      NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW() NOT NULL,
+    PRIMARY KEY (uploader_id, storage_key)
+);
+
+-- Keys already attached are credited to the author of the first post using them
+INSERT INTO uploads (storage_key, uploader_id)
+SELECT DISTINCT ON (a.storage_key) a.storage_key, p.author_id
+FROM post_attachments a
+JOIN all_posts p ON p.id = a.post_id
+WHERE a.storage_key <> ''
+ORDER BY a.storage_key, p.created_at, p.id;
@@ -0,0 +1,2 @@
+-- Cleanup events check whether another post still uses a storage key
+CREATE INDEX idx_post_attachments_storage_key ON post_attachments (storage_key) WHERE storage_key <> '';
@@ -0,0 +1 @@
+-- The original spelling of the tags is not kept, there is nothing to restore
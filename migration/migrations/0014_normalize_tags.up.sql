@@ -0,0 +1,30 @@
+-- Tags written before they were normalized: lowercase, without # and spaces,
+-- deduplicated in the order of their first occurrence (util.NormalizeTag)
+CREATE FUNCTION pg_temp.normalize_tag(tag TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE AS $$ SELECT lower(btrim(ltrim(btrim(tag), '#'))) $$;
+
+CREATE FUNCTION pg_temp.normalize_tags(tags TEXT[]) RETURNS TEXT[]
+    LANGUAGE SQL IMMUTABLE AS $$
+    SELECT COALESCE(array_agg(t ORDER BY pos), '{}')
+    FROM (
+        SELECT pg_temp.normalize_tag(tag) AS t, min(ord) AS pos
+        FROM unnest(tags) WITH ORDINALITY AS u(tag, ord)
+        GROUP BY 1
+    ) normalized
+    WHERE t <> ''
+$$;
+
+UPDATE posts SET tags = pg_temp.normalize_tags(tags)
+WHERE tags <> pg_temp.normalize_tags(tags);
+
+UPDATE posts_archive SET tags = pg_temp.normalize_tags(tags)
+WHERE tags <> pg_temp.normalize_tags(tags);
+
+INSERT INTO tag_follows (follower_id, tag, created_at)
+SELECT follower_id, pg_temp.normalize_tag(tag), min(created_at)
+FROM tag_follows
+WHERE tag <> pg_temp.normalize_tag(tag) AND pg_temp.normalize_tag(tag) <> ''
+GROUP BY 1, 2
+ON CONFLICT (follower_id, tag) DO NOTHING;
+
+DELETE FROM tag_follows WHERE tag <> pg_temp.normalize_tag(tag);
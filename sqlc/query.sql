@@ -1,25 +1,363 @@
+-- Queries of postRepository; the Go code in internal/repository/db is
+-- generated from this file with `sqlc generate` (see sqlc.yaml).
+
 -- name: CreatePost :one
-INSERT INTO posts (title, content, author_id, tags)
-VALUES ($1, $2, $3, $4)
-RETURNING id, title, content, author_id, tags, views, likes_count, comments_count, created_at, updated_at;
+INSERT INTO posts (title, content, content_format, content_html, content_text, excerpt,
+                   author_id, author_username, author_avatar_url, tags, status)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, created_at, updated_at;
 
--- name: GetPostByID :one
-SELECT id, title, content, author_id, tags, views, likes_count, comments_count, created_at, updated_at
+-- name: GetPost :one
+SELECT id, title, content, content_format, content_html, excerpt,
+       author_id, author_username, author_avatar_url,
+       tags, status, views, likes_count, comments_count, created_at, updated_at, deleted_at
 FROM posts
 WHERE id = $1 AND deleted_at IS NULL;
 
+-- name: GetArchivedPost :one
+SELECT id, title, content, content_format, content_html, excerpt,
+       author_id, author_username, author_avatar_url,
+       tags, status, views, likes_count, comments_count, created_at, updated_at, deleted_at
+FROM posts_archive
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UpdatePost :one
+-- NULL arguments leave the column unchanged.
+UPDATE posts SET
+    title          = COALESCE(sqlc.narg('title'), title),
+    content        = COALESCE(sqlc.narg('content'), content),
+    content_format = COALESCE(sqlc.narg('content_format'), content_format),
+    content_html   = COALESCE(sqlc.narg('content_html'), content_html),
+    content_text   = COALESCE(sqlc.narg('content_text'), content_text),
+    excerpt        = COALESCE(sqlc.narg('excerpt'), excerpt),
+    tags           = COALESCE(sqlc.narg('tags')::text[], tags),
+    status         = COALESCE(sqlc.narg('status'), status),
+    updated_at     = NOW()
+WHERE id = sqlc.arg('id') AND deleted_at IS NULL
+RETURNING id, title, content, content_format, content_html, excerpt,
+          author_id, author_username, author_avatar_url,
+          tags, status, views, likes_count, comments_count, created_at, updated_at, deleted_at;
+
+-- name: DeletePost :execrows
+UPDATE posts SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: InsertAttachment :one
+INSERT INTO post_attachments (post_id, position, url, storage_key, mime_type, size_bytes, width, height, alt_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id;
+
+-- name: DeleteAttachments :exec
+DELETE FROM post_attachments WHERE post_id = $1;
+
+-- name: ListAttachments :many
+SELECT id, url, storage_key, mime_type, size_bytes, width, height, alt_text
+FROM post_attachments
+WHERE post_id = $1
+ORDER BY position, id;
+
+-- name: AddUpload :exec
+INSERT INTO uploads (storage_key, uploader_id) VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: OwnedUploadKeys :many
+SELECT storage_key FROM uploads
+WHERE uploader_id = sqlc.arg('uploader_id') AND storage_key = ANY(sqlc.arg('keys')::text[]);
+
+-- name: AttachmentKeysInUse :many
+-- Keys still attached to a live post other than except_post_id
+SELECT DISTINCT a.storage_key
+FROM post_attachments a
+JOIN all_posts p ON p.id = a.post_id
+WHERE a.storage_key = ANY(sqlc.arg('keys')::text[])
+  AND a.post_id <> sqlc.arg('except_post_id') AND p.deleted_at IS NULL;
+
+-- name: InsertMentions :exec
+INSERT INTO post_mentions (post_id, username)
+SELECT sqlc.arg('post_id')::bigint, unnest(sqlc.arg('usernames')::text[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteMentionsExcept :exec
+DELETE FROM post_mentions
+WHERE post_id = sqlc.arg('post_id') AND NOT (username = ANY(sqlc.arg('keep')::text[]));
+
+-- name: ListMentions :many
+SELECT username FROM post_mentions
+WHERE post_id = $1
+ORDER BY created_at, username;
+
+-- name: ListMentionsOfPosts :many
+SELECT post_id, username FROM post_mentions
+WHERE post_id = ANY(sqlc.arg('post_ids')::bigint[])
+ORDER BY created_at, username;
+
 -- name: ListPostsNew :many
-SELECT id, title, content, author_id, tags, views, likes_count, comments_count, created_at, updated_at
-FROM posts
-WHERE deleted_at IS NULL
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author')::text IS NULL OR author_username = sqlc.narg('author'))
+  AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
 ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListPostsTop :many
+-- With a period, posts are ranked by the likes received within it.
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author')::text IS NULL OR author_username = sqlc.narg('author'))
+  AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
+ORDER BY
+    CASE WHEN sqlc.narg('period')::text IS NULL THEN 0 ELSE (
+        SELECT COUNT(*) FROM post_likes pl
+        WHERE pl.post_id = all_posts.id AND pl.created_at >= NOW() - sqlc.narg('period')::text::interval
+    ) END DESC,
+    likes_count DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- The hot queries must match the Score of the rankings in internal/util/hot_score.go.
+
+-- name: ListPostsHotLinear :many
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author')::text IS NULL OR author_username = sqlc.narg('author'))
+  AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
+ORDER BY (likes_count * sqlc.arg('likes_weight')::float8
+          + comments_count * sqlc.arg('comments_weight')::float8
+          + sqlc.arg('views_weight')::float8 * ln(views + 1)) DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListPostsHotReddit :many
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author')::text IS NULL OR author_username = sqlc.narg('author'))
+  AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
+ORDER BY (log(GREATEST(likes_count * sqlc.arg('likes_weight')::float8
+                       + comments_count * sqlc.arg('comments_weight')::float8, 1))
+          + (EXTRACT(EPOCH FROM created_at) - 1134028003) / 45000) DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListPostsHotHN :many
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author')::text IS NULL OR author_username = sqlc.narg('author'))
+  AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
+ORDER BY ((likes_count * sqlc.arg('likes_weight')::float8
+           + comments_count * sqlc.arg('comments_weight')::float8
+           + sqlc.arg('views_weight')::float8 * ln(views + 1))
+          / power(GREATEST(EXTRACT(EPOCH FROM (NOW() - created_at)) / 3600, 0) + 2, sqlc.arg('gravity')::float8)) DESC, id DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: SearchPosts :many
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM all_posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND search_vector @@ plainto_tsquery('russian', sqlc.arg('query'))
+  AND (sqlc.arg('include_archived')::bool OR NOT archived)
+ORDER BY ts_rank(search_vector, plainto_tsquery('russian', sqlc.arg('query'))) DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListAuthorPosts :many
+-- status: published (default), draft, deleted or all. Deleted posts include
+-- the archived ones, like the author stats.
+SELECT id, title, content, content_format, content_html, excerpt,
+       author_id, author_username, author_avatar_url,
+       tags, status, views, likes_count, comments_count, created_at, updated_at, deleted_at
+FROM all_posts
+WHERE author_id = sqlc.arg('author_id')
+  AND CASE sqlc.arg('status')::text
+      WHEN 'all'     THEN TRUE
+      WHEN 'deleted' THEN deleted_at IS NOT NULL
+      WHEN 'draft'   THEN deleted_at IS NULL AND status = 'draft'
+      ELSE deleted_at IS NULL AND status = 'published'
+  END
+  AND (sqlc.arg('include_archived')::bool OR NOT archived OR sqlc.arg('status')::text = 'deleted')
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetAuthorTotals :one
+SELECT COUNT(*) AS total_posts,
+       COALESCE(SUM(likes_count), 0)::bigint AS likes_received,
+       COALESCE(SUM(views), 0)::bigint AS views
+FROM all_posts
+WHERE author_id = $1 AND deleted_at IS NULL AND status = 'published';
+
+-- name: ListAuthorTopTags :many
+SELECT tag::text AS tag, COUNT(*) AS cnt
+FROM all_posts, unnest(tags) AS tag
+WHERE author_id = sqlc.arg('author_id') AND deleted_at IS NULL AND status = 'published'
+GROUP BY tag
+ORDER BY cnt DESC, tag
+LIMIT sqlc.arg('limit');
+
+-- Counters and author updates also apply to archived posts.
 
 -- name: IncrementView :exec
-UPDATE posts SET views = views + 1 WHERE id = $1;
+WITH active AS (UPDATE posts SET views = views + 1 WHERE id = $1)
+UPDATE posts_archive SET views = views + 1 WHERE id = $1;
 
 -- name: IncrementLike :exec
-UPDATE posts SET likes_count = likes_count + 1 WHERE id = $1;
+WITH active AS (UPDATE posts SET likes_count = likes_count + 1 WHERE id = $1)
+UPDATE posts_archive SET likes_count = likes_count + 1 WHERE id = $1;
 
 -- name: DecrementLike :exec
-UPDATE posts SET likes_count = likes_count - 1 WHERE id = $1 AND likes_count > 0;
\ No newline at end of file
+WITH active AS (UPDATE posts SET likes_count = GREATEST(likes_count - 1, 0) WHERE id = $1)
+UPDATE posts_archive SET likes_count = GREATEST(likes_count - 1, 0) WHERE id = $1;
+
+-- name: IncrementComments :exec
+WITH active AS (UPDATE posts SET comments_count = comments_count + 1 WHERE id = $1)
+UPDATE posts_archive SET comments_count = comments_count + 1 WHERE id = $1;
+
+-- name: DecrementComments :exec
+WITH active AS (UPDATE posts SET comments_count = GREATEST(comments_count - 1, 0) WHERE id = $1)
+UPDATE posts_archive SET comments_count = GREATEST(comments_count - 1, 0) WHERE id = $1;
+
+-- name: AdjustComments :exec
+WITH active AS (
+    UPDATE posts SET comments_count = GREATEST(comments_count + sqlc.arg('delta')::bigint, 0) WHERE id = sqlc.arg('id')
+)
+UPDATE posts_archive SET comments_count = GREATEST(comments_count + sqlc.arg('delta')::bigint, 0) WHERE id = sqlc.arg('id');
+
+-- name: AddLike :exec
+INSERT INTO post_likes (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;
+
+-- name: RemoveLike :exec
+DELETE FROM post_likes WHERE post_id = $1 AND user_id = $2;
+
+-- name: HasLiked :one
+SELECT EXISTS(SELECT 1 FROM post_likes WHERE post_id = $1 AND user_id = $2);
+
+-- name: LikedPostIDs :many
+SELECT post_id FROM post_likes
+WHERE user_id = sqlc.arg('user_id') AND post_id = ANY(sqlc.arg('post_ids')::bigint[]);
+
+-- name: ListLikedPosts :many
+SELECT p.id, p.title, p.content, p.content_format, p.content_html, p.excerpt,
+       p.author_id, p.author_username, p.author_avatar_url,
+       p.tags, p.status, p.views, p.likes_count, p.comments_count, p.created_at, p.updated_at, p.deleted_at,
+       pl.created_at AS liked_at
+FROM post_likes pl
+JOIN all_posts p ON p.id = pl.post_id
+WHERE pl.user_id = sqlc.arg('user_id') AND p.deleted_at IS NULL AND p.status = 'published'
+  AND (sqlc.narg('before_time')::timestamptz IS NULL
+       OR (pl.created_at, pl.post_id) < (sqlc.narg('before_time'), sqlc.narg('before_id')::bigint))
+ORDER BY pl.created_at DESC, pl.post_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListPostLikers :many
+SELECT user_id, created_at FROM post_likes
+WHERE post_id = sqlc.arg('post_id')
+  AND (sqlc.narg('before_time')::timestamptz IS NULL
+       OR (created_at, user_id) < (sqlc.narg('before_time'), sqlc.narg('before_id')::bigint))
+ORDER BY created_at DESC, user_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: AddBookmark :exec
+INSERT INTO post_bookmarks (post_id, user_id, folder) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO UPDATE SET folder = EXCLUDED.folder;
+
+-- name: RemoveBookmark :execrows
+DELETE FROM post_bookmarks WHERE post_id = $1 AND user_id = $2;
+
+-- name: BookmarkedPostIDs :many
+SELECT post_id FROM post_bookmarks
+WHERE user_id = sqlc.arg('user_id') AND post_id = ANY(sqlc.arg('post_ids')::bigint[]);
+
+-- name: ListBookmarks :many
+SELECT p.id, p.title, p.content, p.content_format, p.content_html, p.excerpt,
+       p.author_id, p.author_username, p.author_avatar_url,
+       p.tags, p.status, p.views, p.likes_count, p.comments_count, p.created_at, p.updated_at, p.deleted_at,
+       b.folder, b.created_at AS bookmarked_at
+FROM post_bookmarks b
+JOIN all_posts p ON p.id = b.post_id
+WHERE b.user_id = sqlc.arg('user_id') AND p.deleted_at IS NULL AND p.status = 'published'
+  AND (sqlc.narg('folder')::text IS NULL OR b.folder = sqlc.narg('folder'))
+  AND (sqlc.narg('before_time')::timestamptz IS NULL
+       OR (b.created_at, b.post_id) < (sqlc.narg('before_time'), sqlc.narg('before_id')::bigint))
+ORDER BY b.created_at DESC, b.post_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: UpdateAuthorInfo :exec
+WITH active AS (UPDATE posts SET author_username = $2, author_avatar_url = $3 WHERE author_id = $1)
+UPDATE posts_archive SET author_username = $2, author_avatar_url = $3 WHERE author_id = $1;
+
+-- name: AnonymizeAuthorPosts :exec
+WITH active AS (UPDATE posts SET author_username = $2, author_avatar_url = '' WHERE author_id = $1)
+UPDATE posts_archive SET author_username = $2, author_avatar_url = '' WHERE author_id = $1;
+
+-- name: SoftDeleteAuthorPosts :exec
+WITH active AS (UPDATE posts SET deleted_at = NOW() WHERE author_id = $1 AND deleted_at IS NULL)
+UPDATE posts_archive SET deleted_at = NOW() WHERE author_id = $1 AND deleted_at IS NULL;
+
+-- name: SetAuthorPostsStatus :exec
+WITH active AS (
+    UPDATE posts SET status = sqlc.arg('to_status') WHERE author_id = sqlc.arg('author_id') AND status = sqlc.arg('from_status')
+)
+UPDATE posts_archive SET status = sqlc.arg('to_status') WHERE author_id = sqlc.arg('author_id') AND status = sqlc.arg('from_status');
+
+-- name: AddUserFollow :exec
+INSERT INTO user_follows (follower_id, author_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;
+
+-- name: RemoveUserFollow :exec
+DELETE FROM user_follows WHERE follower_id = $1 AND author_id = $2;
+
+-- name: AddTagFollow :exec
+INSERT INTO tag_follows (follower_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING;
+
+-- name: RemoveTagFollow :exec
+DELETE FROM tag_follows WHERE follower_id = $1 AND tag = $2;
+
+-- name: ListFeed :many
+SELECT id, title, excerpt, author_id, author_username, author_avatar_url,
+       tags, views, likes_count, comments_count, created_at, updated_at
+FROM posts
+WHERE deleted_at IS NULL AND status = 'published'
+  AND (author_id IN (SELECT uf.author_id FROM user_follows uf WHERE uf.follower_id = sqlc.arg('user_id'))
+       OR tags && ARRAY(SELECT tf.tag FROM tag_follows tf WHERE tf.follower_id = sqlc.arg('user_id'))::text[])
+  AND (sqlc.narg('before_time')::timestamptz IS NULL
+       OR (created_at, id) < (sqlc.narg('before_time'), sqlc.narg('before_id')::bigint))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListPostsAfterID :many
+SELECT id, title, content, content_format, content_html, excerpt,
+       author_id, author_username, author_avatar_url,
+       tags, status, views, likes_count, comments_count, created_at, updated_at, deleted_at
+FROM all_posts
+WHERE id > sqlc.arg('after_id') AND deleted_at IS NULL AND status = 'published'
+  AND (sqlc.narg('author_id')::bigint IS NULL OR author_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('since')::timestamptz IS NULL OR created_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamptz IS NULL OR created_at < sqlc.narg('until'))
+ORDER BY id
+LIMIT sqlc.arg('limit');
+
+-- name: ArchivePosts :execrows
+-- Moves a batch of soft-deleted posts and of non-draft posts created before
+-- the cutoff. SKIP LOCKED lets several instances archive at once.
+WITH moved AS (
+    DELETE FROM posts
+    WHERE id IN (
+        SELECT id FROM posts
+        WHERE deleted_at IS NOT NULL
+           OR (created_at < sqlc.arg('created_before') AND status <> 'draft')
+        ORDER BY id
+        LIMIT sqlc.arg('limit')
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING *
+)
+INSERT INTO posts_archive
+SELECT moved.*, NOW() FROM moved;